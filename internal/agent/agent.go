@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"time"
 
 	e2e "github.com/newrelic/newrelic-integration-e2e-action/internal"
 	"github.com/newrelic/newrelic-integration-e2e-action/internal/spec"
@@ -35,6 +36,29 @@ type Agent interface {
 	SetUp(scenario spec.Scenario) error
 	Run(scenarioTag string) error
 	Stop() error
+	Environment() Environment
+	Logs(since, until time.Time) (string, error)
+}
+
+// Environment holds the docker-compose file and temporary directories backing an agent run, so it can
+// be inspected and torn down after the process that created it has finished.
+type Environment struct {
+	DockerComposePath string `yaml:"docker_compose_path"`
+	ContainerName     string `yaml:"container_name"`
+	ConfigsDir        string `yaml:"configs_dir"`
+	ExportersDir      string `yaml:"exporters_dir"`
+	BinsDir           string `yaml:"bins_dir"`
+	// RemoveComposeFile is set when the compose file is the embedded default one, written to a temp dir.
+	RemoveComposeFile bool `yaml:"remove_compose_file"`
+}
+
+// EnvVars returns the variables the docker-compose file needs to mount the temporary directories.
+func (e Environment) EnvVars() map[string]string {
+	return map[string]string{
+		integrationsCfgDirEnv: e.ConfigsDir,
+		integrationsBinDirEnv: e.BinsDir,
+		exportersDirEnv:       e.ExportersDir,
+	}
 }
 
 type agent struct {
@@ -220,17 +244,8 @@ func (a *agent) Run(scenarioTag string) error {
 	// Temporary directories with configs and binaries are passed to the docker-compose
 	// through env vars. The docker compose is resposable for mounting this directories
 	// so the Agent automatically executes the integrations.
-
-	if err := os.Setenv(integrationsCfgDirEnv, a.configsDir); err != nil {
-		return fmt.Errorf("fail to set %s env: %w", integrationsCfgDirEnv, err)
-	}
-
-	if err := os.Setenv(integrationsBinDirEnv, a.binsDir); err != nil {
-		return fmt.Errorf("fail to set %s env: %w", integrationsBinDirEnv, err)
-	}
-
-	if err := os.Setenv(exportersDirEnv, a.exportersDir); err != nil {
-		return fmt.Errorf("fail to set %s env: %w", exportersDirEnv, err)
+	if err := setEnvVars(a.Environment().EnvVars()); err != nil {
+		return err
 	}
 
 	return dockercompose.Run(a.dockerComposePath, a.containerName, envVars)
@@ -238,31 +253,71 @@ func (a *agent) Run(scenarioTag string) error {
 
 func (a *agent) Stop() error {
 	if a.logger.GetLevel() == logrus.DebugLevel {
-		a.logger.Debug(dockercompose.Logs(a.dockerComposePath, a.containerName))
+		logs, err := a.Logs(time.Time{}, time.Time{})
+		if err != nil {
+			a.logger.Errorf("reading agent logs: %v", err)
+		}
+		a.logger.Debug(logs)
 	}
 
-	if err := dockercompose.Down(a.dockerComposePath); err != nil {
+	return Teardown(a.Environment())
+}
+
+// Logs returns the logs of the agent container written between since and until, a zero time leaves the
+// bound open.
+func (a *agent) Logs(since, until time.Time) (string, error) {
+	return dockercompose.Logs(a.dockerComposePath, a.containerName, since, until)
+}
+
+// Environment returns the compose file and temporary directories used by the agent.
+func (a *agent) Environment() Environment {
+	return Environment{
+		DockerComposePath: a.dockerComposePath,
+		ContainerName:     a.containerName,
+		ConfigsDir:        a.configsDir,
+		ExportersDir:      a.exportersDir,
+		BinsDir:           a.binsDir,
+		RemoveComposeFile: a.agentBuildContext == "",
+	}
+}
+
+// Teardown stops the agent container and removes the temporary directories of the given environment.
+func Teardown(env Environment) error {
+	if err := setEnvVars(env.EnvVars()); err != nil {
+		return err
+	}
+
+	if err := dockercompose.Down(env.DockerComposePath); err != nil {
 		return err
 	}
 
 	// Remove compose file when using default.
-	if a.agentBuildContext == "" {
-		if err := os.RemoveAll(a.dockerComposePath); err != nil {
+	if env.RemoveComposeFile {
+		if err := os.RemoveAll(env.DockerComposePath); err != nil {
 			return err
 		}
 	}
 
-	if err := os.RemoveAll(a.binsDir); err != nil {
+	if err := os.RemoveAll(env.BinsDir); err != nil {
 		return err
 	}
 
-	if err := os.RemoveAll(a.exportersDir); err != nil {
+	if err := os.RemoveAll(env.ExportersDir); err != nil {
 		return err
 	}
 
-	if err := os.RemoveAll(a.configsDir); err != nil {
+	if err := os.RemoveAll(env.ConfigsDir); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+func setEnvVars(envVars map[string]string) error {
+	for envKey, envValue := range envVars {
+		if err := os.Setenv(envKey, envValue); err != nil {
+			return fmt.Errorf("fail to set %s env: %w", envKey, err)
+		}
+	}
+	return nil
+}
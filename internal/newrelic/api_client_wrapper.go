@@ -13,6 +13,7 @@ import (
 type ApiClient interface {
 	Query(accountId int, query string) (*nrdb.NRDBResultContainer, error)
 	GetEntity(guid *common.EntityGUID) (*entities.EntityInterface, error)
+	NerdGraphQuery(query string, variables map[string]interface{}, respBody interface{}) error
 }
 
 type ApiClientWrapper struct {
@@ -38,3 +39,7 @@ func (a ApiClientWrapper) Query(accountId int, query string) (*nrdb.NRDBResultCo
 func (a ApiClientWrapper) GetEntity(guid *common.EntityGUID) (*entities.EntityInterface, error) {
 	return a.client.Entities.GetEntity(*guid)
 }
+
+func (a ApiClientWrapper) NerdGraphQuery(query string, variables map[string]interface{}, respBody interface{}) error {
+	return a.client.NerdGraph.QueryWithResponse(query, variables, respBody)
+}
@@ -3,6 +3,9 @@ package newrelic
 import (
 	"errors"
 	"fmt"
+	"math"
+	"reflect"
+	"regexp"
 	"strings"
 
 	"github.com/newrelic/newrelic-client-go/pkg/nrdb"
@@ -16,6 +19,36 @@ var (
 	ErrNotExpectedResult = errors.New("query did not return expected results")
 )
 
+// CheckNRQLResults checks the results of the query as NRQLQuery does, so testers already holding the
+// results do not need to query them again.
+func CheckNRQLResults(query string, queryResults []nrdb.NRDBResult, errorExpected bool, expectedResults []spec.TestNRQLExpectedResult, timeseries *spec.TestNRQLTimeseries) error {
+	if timeseries != nil {
+		if err := nrqlQueryTimeseriesTest(queryResults, *timeseries); err != nil {
+			return fmt.Errorf("%w: %s", err, query)
+		}
+		return nil
+	}
+
+	if expectedResults == nil {
+		// Backwards compatible test
+		err := nrqlQueryDefaultTest(queryResults)
+		if err != nil && !errorExpected {
+			return fmt.Errorf("querying: %w: %s", err, query)
+		}
+		if err == nil && errorExpected {
+			return fmt.Errorf("running %q: %w", query, ErrExpected)
+		}
+		return nil
+	}
+
+	// Expected value test
+	testErr := nrqlQueryExpectedValueTest(queryResults, expectedResults)
+	if testErr != nil {
+		return fmt.Errorf("%w: %s", testErr, query)
+	}
+	return nil
+}
+
 func nrqlQueryDefaultTest(queryResults []nrdb.NRDBResult) error {
 	if len(queryResults) == 0 {
 		return ErrNoResult
@@ -40,6 +73,12 @@ func validValue(queryResults []nrdb.NRDBResult) bool {
 }
 
 func nrqlQueryExpectedValueTest(queryResults []nrdb.NRDBResult, expectedResults []spec.TestNRQLExpectedResult) error {
+	for _, expectedResult := range expectedResults {
+		if expectedResult.IsRowSelector() {
+			return nrqlQuerySelectedRowsTest(queryResults, expectedResults)
+		}
+	}
+
 	if len(expectedResults) != len(queryResults) {
 		return fmt.Errorf("%w: expected %d got %d", ErrResultNumber, len(expectedResults), len(queryResults))
 	}
@@ -53,17 +92,82 @@ func nrqlQueryExpectedValueTest(queryResults []nrdb.NRDBResult, expectedResults
 	return nil
 }
 
+// nrqlQuerySelectedRowsTest asserts each expected result against the rows matching its facet values, or every
+// row if it has no facet, regardless of their order. By default every selected row must satisfy the expected
+// result, or at least one of them with the `any` rows mode.
+func nrqlQuerySelectedRowsTest(queryResults []nrdb.NRDBResult, expectedResults []spec.TestNRQLExpectedResult) error {
+	for _, expectedResult := range expectedResults {
+		var rows []nrdb.NRDBResult
+		for _, row := range queryResults {
+			if matchesFacet(row, expectedResult.Facet) {
+				rows = append(rows, row)
+			}
+		}
+		if len(rows) == 0 {
+			return fmt.Errorf("%w: no rows for facet %v", ErrNoResult, expectedResult.Facet)
+		}
+
+		var comparisonErrs []string
+		for _, row := range rows {
+			if err := compareResults(row[expectedResult.Key], expectedResult); err != nil {
+				comparisonErrs = append(comparisonErrs, fmt.Sprintf("facet %v: %s", row["facet"], err))
+			}
+		}
+
+		mode := spec.RowsEvery
+		satisfied := len(comparisonErrs) == 0
+		if expectedResult.Rows == spec.RowsAny {
+			mode = spec.RowsAny
+			satisfied = len(comparisonErrs) < len(rows)
+		}
+		if !satisfied {
+			return fmt.Errorf("%w: for key '%s' in %s of %d rows: %s", ErrNotExpectedResult, expectedResult.Key,
+				mode, len(rows), strings.Join(comparisonErrs, "; "))
+		}
+	}
+	return nil
+}
+
+func matchesFacet(row nrdb.NRDBResult, facet map[string]any) bool {
+	for key, expected := range facet {
+		if !equalResults(row[key], expected) {
+			return false
+		}
+	}
+	return true
+}
+
 func compareResults(actualResult any, expectedResult spec.TestNRQLExpectedResult) error {
-	expectedExactResult := expectedResult.Value
-	if expectedExactResult != nil {
+	switch {
+	case expectedResult.Value != nil:
 		// We are checking for an exact value
-		expectedExactResult = preprocessResult(expectedExactResult)
+		expectedExactResult := preprocessResult(expectedResult.Value)
 		actualResult = preprocessResult(actualResult)
 
-		if expectedExactResult == actualResult {
+		if equalResults(expectedExactResult, actualResult) {
 			return nil
 		}
 		return fmt.Errorf("%w - expected: '%s', got '%s'", ErrAssertionFailure, expectedExactResult, actualResult)
+	case expectedResult.NotEqual != nil:
+		notExpectedResult := preprocessResult(expectedResult.NotEqual)
+		if !equalResults(notExpectedResult, actualResult) {
+			return nil
+		}
+		return fmt.Errorf("%w - expected value different from '%v', got '%v'", ErrAssertionFailure, notExpectedResult, actualResult)
+	case expectedResult.Regex != "":
+		return checkRegex(actualResult, expectedResult.Regex)
+	case expectedResult.Contains != nil:
+		return checkContains(actualResult, expectedResult.Contains)
+	case expectedResult.OneOf != nil:
+		return checkOneOf(actualResult, expectedResult.OneOf)
+	case expectedResult.Type != "":
+		return checkType(actualResult, expectedResult.Type)
+	case expectedResult.Approx != nil:
+		actualFloat, err := extractFloat(actualResult)
+		if err != nil {
+			return err
+		}
+		return checkApprox(actualFloat, *expectedResult.Approx)
 	}
 
 	// We are checking for a bounded value
@@ -74,6 +178,85 @@ func compareResults(actualResult any, expectedResult spec.TestNRQLExpectedResult
 	return checkBounds(actualFloat, expectedResult.LowerBoundedValue, expectedResult.UpperBoundedValue)
 }
 
+func checkRegex(actualResult any, expr string) error {
+	// The regex has already been validated when parsing the spec file.
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return err
+	}
+	actualString, ok := actualResult.(string)
+	if !ok {
+		return fmt.Errorf("%w: string", ErrTypeAssertion)
+	}
+	if re.MatchString(actualString) {
+		return nil
+	}
+	return fmt.Errorf("%w - expected value matching '%s', got '%s'", ErrAssertionFailure, expr, actualString)
+}
+
+// checkContains checks that a string result contains the expected substring, or that a list result,
+// like the ones returned by `uniques()`, contains the expected element.
+func checkContains(actualResult any, expected any) error {
+	switch typedResult := actualResult.(type) {
+	case string:
+		if expectedString, ok := expected.(string); ok && strings.Contains(typedResult, expectedString) {
+			return nil
+		}
+	case []interface{}:
+		for _, element := range typedResult {
+			if equalResults(element, expected) {
+				return nil
+			}
+		}
+	default:
+		return fmt.Errorf("%w: string or list", ErrTypeAssertion)
+	}
+	return fmt.Errorf("%w - expected value containing '%v', got '%v'", ErrAssertionFailure, expected, actualResult)
+}
+
+func checkOneOf(actualResult any, oneOf []any) error {
+	for _, expected := range oneOf {
+		if equalResults(expected, actualResult) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w - expected one of %v, got '%v'", ErrAssertionFailure, oneOf, actualResult)
+}
+
+func checkType(actualResult any, expectedType string) error {
+	var actualType string
+	switch actualResult.(type) {
+	case nil:
+		actualType = spec.ResultTypeNull
+	case string:
+		actualType = spec.ResultTypeString
+	case bool:
+		actualType = spec.ResultTypeBool
+	case int, float64:
+		actualType = spec.ResultTypeNumber
+	default:
+		actualType = fmt.Sprintf("%T", actualResult)
+	}
+
+	if actualType == expectedType {
+		return nil
+	}
+	return fmt.Errorf("%w - expected value of type %s, got %s '%v'", ErrAssertionFailure, expectedType, actualType, actualResult)
+}
+
+// checkApprox succeeds if the value is within any of the absolute or relative tolerances.
+func checkApprox(actualFloat float64, approx spec.Approx) error {
+	diff := math.Abs(actualFloat - *approx.Value)
+	if approx.Tolerance > 0 && diff <= approx.Tolerance {
+		return nil
+	}
+	if approx.RelativeTolerance > 0 && diff <= approx.RelativeTolerance*math.Abs(*approx.Value) {
+		return nil
+	}
+	return fmt.Errorf("%w - expected value approximately %f (tolerance %g, relative tolerance %g), got %f",
+		ErrAssertionFailure, *approx.Value, approx.Tolerance, approx.RelativeTolerance, actualFloat)
+}
+
 // preprocessResult convert integers into floats, `"nil" into nil`, and string booleans (`"false"`) into typed booleans (`false`)
 func preprocessResult(result any) any {
 	switch typedResult := result.(type) {
@@ -100,6 +283,31 @@ func preprocessResult(result any) any {
 	return result
 }
 
+// equalResults compares the results once preprocessed, including the elements of lists like the `facet` of
+// queries faceted by several attributes, which cannot be compared with ==.
+func equalResults(a, b any) bool {
+	return reflect.DeepEqual(normalizeResult(a), normalizeResult(b))
+}
+
+// normalizeResult preprocesses the result and, recursively, the elements of lists and maps.
+func normalizeResult(result any) any {
+	switch typedResult := result.(type) {
+	case []interface{}:
+		normalized := make([]interface{}, 0, len(typedResult))
+		for _, element := range typedResult {
+			normalized = append(normalized, normalizeResult(element))
+		}
+		return normalized
+	case map[string]interface{}:
+		normalized := make(map[string]interface{}, len(typedResult))
+		for key, value := range typedResult {
+			normalized[key] = normalizeResult(value)
+		}
+		return normalized
+	}
+	return preprocessResult(result)
+}
+
 func extractFloat(result any) (float64, error) {
 	result = preprocessResult(result)
 	floatResult, ok := result.(float64)
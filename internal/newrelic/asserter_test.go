@@ -1,6 +1,11 @@
 package newrelic
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/newrelic/newrelic-client-go/pkg/nrdb"
+	"github.com/newrelic/newrelic-integration-e2e-action/internal/spec"
+)
 
 func Test_checkBounds(t *testing.T) {
 	lowerResult := 5.0
@@ -70,3 +75,226 @@ func Test_checkBounds(t *testing.T) {
 		})
 	}
 }
+
+func Test_compareResults(t *testing.T) {
+	approxValue := 100.0
+
+	tests := []struct {
+		name           string
+		actualResult   any
+		expectedResult spec.TestNRQLExpectedResult
+		wantErr        bool
+	}{
+		{
+			name:           "when the value is different from not_equal it should return no error",
+			actualResult:   "up",
+			expectedResult: spec.TestNRQLExpectedResult{NotEqual: "down"},
+			wantErr:        false,
+		},
+		{
+			name:           "when the value is equal to not_equal it should return an error",
+			actualResult:   4.0,
+			expectedResult: spec.TestNRQLExpectedResult{NotEqual: 4},
+			wantErr:        true,
+		},
+		{
+			name:           "when the value matches the regex it should return no error",
+			actualResult:   "1.20.2",
+			expectedResult: spec.TestNRQLExpectedResult{Regex: `^\d+\.\d+\.\d+$`},
+			wantErr:        false,
+		},
+		{
+			name:           "when the value does not match the regex it should return an error",
+			actualResult:   "latest",
+			expectedResult: spec.TestNRQLExpectedResult{Regex: `^\d+\.\d+\.\d+$`},
+			wantErr:        true,
+		},
+		{
+			name:           "when the value is not a string for a regex it should return an error",
+			actualResult:   1.0,
+			expectedResult: spec.TestNRQLExpectedResult{Regex: `1`},
+			wantErr:        true,
+		},
+		{
+			name:           "when the string contains the substring it should return no error",
+			actualResult:   "kafka-broker-1",
+			expectedResult: spec.TestNRQLExpectedResult{Contains: "broker"},
+			wantErr:        false,
+		},
+		{
+			name:           "when the list contains the element it should return no error",
+			actualResult:   []interface{}{1.0, 2.0},
+			expectedResult: spec.TestNRQLExpectedResult{Contains: 2},
+			wantErr:        false,
+		},
+		{
+			name:           "when the list does not contain the element it should return an error",
+			actualResult:   []interface{}{"running"},
+			expectedResult: spec.TestNRQLExpectedResult{Contains: "stopped"},
+			wantErr:        true,
+		},
+		{
+			name:           "when the list contains the list element it should return no error",
+			actualResult:   []interface{}{[]interface{}{"orders", 1.0}},
+			expectedResult: spec.TestNRQLExpectedResult{Contains: []any{"orders", 1}},
+			wantErr:        false,
+		},
+		{
+			name:           "when the list value is equal to the expected list it should return no error",
+			actualResult:   []interface{}{"orders", 1.0},
+			expectedResult: spec.TestNRQLExpectedResult{Value: []any{"orders", 1}},
+			wantErr:        false,
+		},
+		{
+			name:           "when the list value is one of the expected values it should return no error",
+			actualResult:   []interface{}{"orders", "eu"},
+			expectedResult: spec.TestNRQLExpectedResult{OneOf: []any{"orders", []any{"orders", "eu"}}},
+			wantErr:        false,
+		},
+		{
+			name:           "when the value is one of the expected values it should return no error",
+			actualResult:   "starting",
+			expectedResult: spec.TestNRQLExpectedResult{OneOf: []any{"running", "starting"}},
+			wantErr:        false,
+		},
+		{
+			name:           "when the value is none of the expected values it should return an error",
+			actualResult:   3.0,
+			expectedResult: spec.TestNRQLExpectedResult{OneOf: []any{1, 2}},
+			wantErr:        true,
+		},
+		{
+			name:           "when the value has the expected type it should return no error",
+			actualResult:   nil,
+			expectedResult: spec.TestNRQLExpectedResult{Type: spec.ResultTypeNull},
+			wantErr:        false,
+		},
+		{
+			name:           "when the value does not have the expected type it should return an error",
+			actualResult:   "4",
+			expectedResult: spec.TestNRQLExpectedResult{Type: spec.ResultTypeNumber},
+			wantErr:        true,
+		},
+		{
+			name:           "when the value is within the relative tolerance it should return no error",
+			actualResult:   104.0,
+			expectedResult: spec.TestNRQLExpectedResult{Approx: &spec.Approx{Value: &approxValue, RelativeTolerance: 0.05}},
+			wantErr:        false,
+		},
+		{
+			name:           "when the value is within the absolute tolerance it should return no error",
+			actualResult:   98.0,
+			expectedResult: spec.TestNRQLExpectedResult{Approx: &spec.Approx{Value: &approxValue, Tolerance: 2}},
+			wantErr:        false,
+		},
+		{
+			name:           "when the value is out of the tolerances it should return an error",
+			actualResult:   110.0,
+			expectedResult: spec.TestNRQLExpectedResult{Approx: &spec.Approx{Value: &approxValue, Tolerance: 2, RelativeTolerance: 0.05}},
+			wantErr:        true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := compareResults(tt.actualResult, tt.expectedResult); (err != nil) != tt.wantErr {
+				t.Errorf("compareResults() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_nrqlQueryExpectedValueTest(t *testing.T) {
+	lowerResult := 5.0
+
+	facetResults := []nrdb.NRDBResult{
+		{"facet": "payments", "topic": "payments", "count": 3.0},
+		{"facet": "orders", "topic": "orders", "count": 10.0},
+	}
+
+	// Queries faceted by several attributes return the facet as a list.
+	multiFacetResults := []nrdb.NRDBResult{
+		{"facet": []interface{}{"orders", "us"}, "topic": "orders", "region": "us", "count": 3.0},
+		{"facet": []interface{}{"orders", "eu"}, "topic": "orders", "region": "eu", "count": 10.0},
+	}
+
+	tests := []struct {
+		name            string
+		queryResults    []nrdb.NRDBResult
+		expectedResults []spec.TestNRQLExpectedResult
+		wantErr         bool
+	}{
+		{
+			name:            "when the rows are matched by index and the number differs it should return an error",
+			queryResults:    facetResults,
+			expectedResults: []spec.TestNRQLExpectedResult{{Key: "count", Value: 10}},
+			wantErr:         true,
+		},
+		{
+			name:         "when the rows are selected by facet regardless of their order it should return no error",
+			queryResults: facetResults,
+			expectedResults: []spec.TestNRQLExpectedResult{
+				{Key: "count", Value: 10, Facet: map[string]any{"topic": "orders"}},
+				{Key: "count", Value: 3, Facet: map[string]any{"topic": "payments"}},
+			},
+			wantErr: false,
+		},
+		{
+			name:            "when the row selected by facet does not satisfy the expected result it should return an error",
+			queryResults:    facetResults,
+			expectedResults: []spec.TestNRQLExpectedResult{{Key: "count", Value: 3, Facet: map[string]any{"topic": "orders"}}},
+			wantErr:         true,
+		},
+		{
+			name:            "when no row matches the facet it should return an error",
+			queryResults:    facetResults,
+			expectedResults: []spec.TestNRQLExpectedResult{{Key: "count", Type: spec.ResultTypeNumber, Facet: map[string]any{"topic": "users"}}},
+			wantErr:         true,
+		},
+		{
+			name:         "when the rows are selected by a multi-attribute facet it should return no error",
+			queryResults: multiFacetResults,
+			expectedResults: []spec.TestNRQLExpectedResult{
+				{Key: "count", Value: 10, Facet: map[string]any{"facet": []any{"orders", "eu"}}},
+				{Key: "count", Value: 3, Facet: map[string]any{"facet": []any{"orders", "us"}}},
+			},
+			wantErr: false,
+		},
+		{
+			name:            "when no row matches the multi-attribute facet it should return an error",
+			queryResults:    multiFacetResults,
+			expectedResults: []spec.TestNRQLExpectedResult{{Key: "count", Value: 10, Facet: map[string]any{"facet": []any{"payments", "eu"}}}},
+			wantErr:         true,
+		},
+		{
+			name:            "when every row satisfies the expected result it should return no error",
+			queryResults:    facetResults,
+			expectedResults: []spec.TestNRQLExpectedResult{{Key: "count", Type: spec.ResultTypeNumber, Rows: spec.RowsEvery}},
+			wantErr:         false,
+		},
+		{
+			name:            "when not every row satisfies the expected result it should return an error",
+			queryResults:    facetResults,
+			expectedResults: []spec.TestNRQLExpectedResult{{Key: "count", LowerBoundedValue: &lowerResult, Rows: spec.RowsEvery}},
+			wantErr:         true,
+		},
+		{
+			name:            "when at least one row satisfies the expected result it should return no error",
+			queryResults:    facetResults,
+			expectedResults: []spec.TestNRQLExpectedResult{{Key: "count", LowerBoundedValue: &lowerResult, Rows: spec.RowsAny}},
+			wantErr:         false,
+		},
+		{
+			name:            "when no row satisfies the expected result it should return an error",
+			queryResults:    facetResults,
+			expectedResults: []spec.TestNRQLExpectedResult{{Key: "count", Value: 1, Rows: spec.RowsAny}},
+			wantErr:         true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := nrqlQueryExpectedValueTest(tt.queryResults, tt.expectedResults); (err != nil) != tt.wantErr {
+				t.Errorf("nrqlQueryExpectedValueTest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -14,10 +14,20 @@ import (
 )
 
 type Client interface {
-	FindEntityGUIDs(sample, metricName, customTagKey, entityTag string, expectedNumber int) ([]common.EntityGUID, error)
+	FindEntityGUIDs(sample, metricName, attribute, customTagKey, entityTag string, timeRange TimeRange, expectedNumber int) ([]common.EntityGUID, error)
 	FindEntityByGUID(guid *common.EntityGUID) (entities.EntityInterface, error)
-	FindEntityMetrics(sample, customTagKey, entityTag string) ([]string, error)
-	NRQLQuery(query, customTagKey, entityTag string, errorExpected bool, expectedResults []spec.TestNRQLExpectedResult) error
+	FindEntityMetrics(sample, customTagKey, entityTag string, timeRange TimeRange) ([]string, error)
+	FindMetricNames(customTagKey, entityTag string, timeRange TimeRange) ([]string, error)
+	FindMetricEntityTypes(customTagKey, entityTag string, timeRange TimeRange) (map[string][]string, error)
+	FindMetricDimensions(metricName, customTagKey, entityTag string, timeRange TimeRange) ([]string, error)
+	FindMetricTypes(metricName, customTagKey, entityTag string, timeRange TimeRange) ([]string, error)
+	FindMetricUnits(metricName, customTagKey, entityTag string, timeRange TimeRange) ([]string, error)
+	FindMetricTimeseries(metricName string, bucketSeconds int, customTagKey, entityTag string, timeRange TimeRange) (map[string][]float64, error)
+	FindMetricValues(metricName, customTagKey, entityTag string, timeRange TimeRange) ([]MetricValues, error)
+	NRQLQuery(query, customTagKey, entityTag string, timeRange TimeRange, errorExpected bool, expectedResults []spec.TestNRQLExpectedResult, timeseries *spec.TestNRQLTimeseries) error
+	FindLogs(customTagKey, entityTag string, timeRange TimeRange) ([]nrdb.NRDBResult, error)
+	FindRelatedEntities(guid common.EntityGUID) ([]Relationship, error)
+	NRQLResults(query, customTagKey, entityTag string, timeRange TimeRange) ([]nrdb.NRDBResult, error)
 }
 
 var (
@@ -28,6 +38,66 @@ var (
 	ErrAssertionFailure = errors.New("assertion failure")
 )
 
+// MetricValues are the minimum and maximum values of a metric reported for an entity. They are nil
+// when the entity did not report any numeric value.
+type MetricValues struct {
+	Entity string
+	Min    *float64
+	Max    *float64
+}
+
+// Relationship is an edge between two entities as returned by the NerdGraph relatedEntities field.
+type Relationship struct {
+	Type       string
+	SourceGUID common.EntityGUID
+	SourceType string
+	SourceName string
+	TargetGUID common.EntityGUID
+	TargetType string
+	TargetName string
+}
+
+// maxRelatedEntitiesPages bounds the pages fetched for a single entity, in case the cursor never ends.
+const maxRelatedEntitiesPages = 50
+
+const relatedEntitiesQuery = `query($guid: EntityGuid!, $cursor: String) {
+  actor {
+    entity(guid: $guid) {
+      relatedEntities(cursor: $cursor) {
+        nextCursor
+        results {
+          type
+          source { guid entity { type name } }
+          target { guid entity { type name } }
+        }
+      }
+    }
+  }
+}`
+
+type relatedEntityVertex struct {
+	GUID   common.EntityGUID `json:"guid"`
+	Entity *struct {
+		Type string `json:"type"`
+		Name string `json:"name"`
+	} `json:"entity"`
+}
+
+type relatedEntitiesResponse struct {
+	Actor struct {
+		Entity *struct {
+			RelatedEntities struct {
+				NextCursor string `json:"nextCursor"`
+				Results    []struct {
+					Type   string              `json:"type"`
+					Source relatedEntityVertex `json:"source"`
+					Target relatedEntityVertex `json:"target"`
+				} `json:"results"`
+			} `json:"relatedEntities"`
+		} `json:"entity"`
+	} `json:"actor"`
+}
+
 type nrClient struct {
 	accountID int
 	apiKey    string
@@ -46,24 +116,29 @@ func NewNrClient(apiKey string, region string, accountID int) *nrClient {
 	}
 }
 
-func (nrc *nrClient) FindEntityGUIDs(sample, metricName, customTagKey, entityTag string, expectedNumber int) ([]common.EntityGUID, error) {
+// FindEntityGUIDs returns the GUIDs of the entities reporting the metric. For event types (i.e.
+// `KafkaBrokerSample`), which have no metricName, the attribute is the one the events must have, and when
+// neither is set the entities of every event of the type are looked for.
+func (nrc *nrClient) FindEntityGUIDs(sample, metricName, attribute, customTagKey, entityTag string, timeRange TimeRange, expectedNumber int) ([]common.EntityGUID, error) {
 	var entityGuids []common.EntityGUID
-	query := fmt.Sprintf("SELECT uniques(entity.guid) from %s where metricName = '%s' where %s = '%s' limit 1", sample, metricName, customTagKey, entityTag)
+	guidAttribute := entityGUIDAttribute(sample)
+	query := entityLookupQuery(sample, metricName, attribute, customTagKey, entityTag, timeRange)
+	resultKey := fmt.Sprintf("uniques.%s", guidAttribute)
 
 	a, err := nrc.client.Query(nrc.accountID, query)
 	if err != nil {
 		return nil, fmt.Errorf("executing query to fetch entity GUIDs %s, %w", query, err)
 	}
 
-	if len(a.Results) < 1 || a.Results[0]["uniques.entity.guid"] == nil {
+	if len(a.Results) < 1 || a.Results[0][resultKey] == nil {
 		return nil, ErrNoResult
 	}
 
-	if results := len(a.Results[0]["uniques.entity.guid"].([]interface{})); results < expectedNumber {
+	if results := len(a.Results[0][resultKey].([]interface{})); results < expectedNumber {
 		return nil, fmt.Errorf("%w: %s: got %d, expected %d", ErrResultNumber, query, results, expectedNumber)
 	}
 
-	for _, g := range a.Results[0]["uniques.entity.guid"].([]interface{}) {
+	for _, g := range a.Results[0][resultKey].([]interface{}) {
 		guid := common.EntityGUID(fmt.Sprintf("%v", g))
 		entityGuids = append(entityGuids, guid)
 	}
@@ -71,6 +146,30 @@ func (nrc *nrClient) FindEntityGUIDs(sample, metricName, customTagKey, entityTag
 	return entityGuids, nil
 }
 
+// entityGUIDAttribute returns the attribute holding the entity GUID: the legacy samples report it as
+// `entityGuid` instead of the `entity.guid` of the dimensional metrics.
+func entityGUIDAttribute(sample string) string {
+	if sample == spec.MetricDataType {
+		return "entity.guid"
+	}
+	return "entityGuid"
+}
+
+func entityLookupQuery(sample, metricName, attribute, customTagKey, entityTag string, timeRange TimeRange) string {
+	return fmt.Sprintf("SELECT uniques(%s) from %s%s where %s = '%s' limit 1%s",
+		entityGUIDAttribute(sample), sample, entityLookupFilter(sample, metricName, attribute), customTagKey, entityTag, timeRange.clause())
+}
+
+func entityLookupFilter(sample, metricName, attribute string) string {
+	switch {
+	case attribute != "":
+		return fmt.Sprintf(" where `%s` IS NOT NULL", attribute)
+	case metricName != "" || sample == spec.MetricDataType:
+		return fmt.Sprintf(" where metricName = '%s'", metricName)
+	}
+	return ""
+}
+
 func (nrc *nrClient) FindEntityByGUID(guid *common.EntityGUID) (entities.EntityInterface, error) {
 	if guid == nil {
 		return nil, ErrNilGUID
@@ -87,8 +186,8 @@ func (nrc *nrClient) FindEntityByGUID(guid *common.EntityGUID) (entities.EntityI
 	return *entity, nil
 }
 
-func (nrc *nrClient) FindEntityMetrics(sample, customTagKey, entityTag string) ([]string, error) {
-	query := fmt.Sprintf("SELECT keyset() from %s where %s = '%s'", sample, customTagKey, entityTag)
+func (nrc *nrClient) FindEntityMetrics(sample, customTagKey, entityTag string, timeRange TimeRange) ([]string, error) {
+	query := fmt.Sprintf("SELECT keyset() from %s where %s = '%s'%s", sample, customTagKey, entityTag, timeRange.clause())
 
 	a, err := nrc.client.Query(nrc.accountID, query)
 	if err != nil {
@@ -100,33 +199,249 @@ func (nrc *nrClient) FindEntityMetrics(sample, customTagKey, entityTag string) (
 	return resultMetrics(a.Results), nil
 }
 
-func (nrc *nrClient) NRQLQuery(query, customTagKey, entityTag string, errorExpected bool, expectedResults []spec.TestNRQLExpectedResult) error {
-	query = fmt.Sprintf("%s WHERE %s = '%s'", query, customTagKey, entityTag)
-	query = strings.ReplaceAll(query, "${SCENARIO_TAG}", entityTag)
+func (nrc *nrClient) FindMetricNames(customTagKey, entityTag string, timeRange TimeRange) ([]string, error) {
+	query := fmt.Sprintf("SELECT uniques(metricName, 10000) as 'names' from Metric where %s = '%s'%s", customTagKey, entityTag, timeRange.clause())
 
 	a, err := nrc.client.Query(nrc.accountID, query)
 	if err != nil {
-		return fmt.Errorf("executing nrql query %s, %w", query, err)
+		return nil, fmt.Errorf("executing query to fetch metric names %s, %w", query, err)
+	}
+	if len(a.Results) < 1 || a.Results[0]["names"] == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoResult, query)
+	}
+
+	names, ok := a.Results[0]["names"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotValid, query)
+	}
+
+	metricNames := make([]string, 0, len(names))
+	for _, n := range names {
+		metricNames = append(metricNames, fmt.Sprintf("%v", n))
+	}
+	return metricNames, nil
+}
+
+// FindMetricEntityTypes returns the entity types reporting each metric name. Metrics reported without
+// entity type are not included.
+func (nrc *nrClient) FindMetricEntityTypes(customTagKey, entityTag string, timeRange TimeRange) (map[string][]string, error) {
+	query := fmt.Sprintf("SELECT uniques(metricName, 10000) as 'names' from Metric where %s = '%s' FACET entity.type LIMIT MAX%s", customTagKey, entityTag, timeRange.clause())
+
+	a, err := nrc.client.Query(nrc.accountID, query)
+	if err != nil {
+		return nil, fmt.Errorf("executing query to fetch metric entity types %s, %w", query, err)
 	}
 
-	if expectedResults == nil {
-		// Backwards compatible test
-		err := nrqlQueryDefaultTest(a.Results)
-		if err != nil && !errorExpected {
-			return fmt.Errorf("querying: %w: %s", err, query)
+	entityTypes := map[string][]string{}
+	for _, r := range a.Results {
+		entityType, ok := r["facet"].(string)
+		if !ok || entityType == "" {
+			continue
 		}
-		if err == nil && errorExpected {
-			return fmt.Errorf("running %q: %w", query, ErrExpected)
+		names, ok := r["names"].([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrNotValid, query)
 		}
-		return nil
+		for _, n := range names {
+			name := fmt.Sprintf("%v", n)
+			entityTypes[name] = append(entityTypes[name], entityType)
+		}
+	}
+	return entityTypes, nil
+}
+
+func (nrc *nrClient) FindMetricDimensions(metricName, customTagKey, entityTag string, timeRange TimeRange) ([]string, error) {
+	query := fmt.Sprintf("SELECT keyset() from Metric where metricName = '%s' where %s = '%s'%s", metricName, customTagKey, entityTag, timeRange.clause())
+
+	a, err := nrc.client.Query(nrc.accountID, query)
+	if err != nil {
+		return nil, fmt.Errorf("executing query to keyset %s, %w", query, err)
+	}
+	if len(a.Results) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoResult, query)
+	}
+	return resultMetrics(a.Results), nil
+}
+
+func (nrc *nrClient) FindMetricTypes(metricName, customTagKey, entityTag string, timeRange TimeRange) ([]string, error) {
+	query := fmt.Sprintf("SELECT uniques(getField(`%s`, type)) as 'types' from Metric where metricName = '%s' where %s = '%s'%s", metricName, metricName, customTagKey, entityTag, timeRange.clause())
+
+	a, err := nrc.client.Query(nrc.accountID, query)
+	if err != nil {
+		return nil, fmt.Errorf("executing query to fetch metric types %s, %w", query, err)
+	}
+	if len(a.Results) < 1 || a.Results[0]["types"] == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoResult, query)
+	}
+
+	types, ok := a.Results[0]["types"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotValid, query)
 	}
 
-	// Expected value test
-	testErr := nrqlQueryExpectedValueTest(a.Results, expectedResults)
-	if testErr != nil {
-		return fmt.Errorf("%w: %s", testErr, query)
+	var metricTypes []string
+	for _, t := range types {
+		metricTypes = append(metricTypes, fmt.Sprintf("%v", t))
+	}
+	return metricTypes, nil
+}
+
+// FindMetricUnits returns the values of the unit attribute stored with the metric, empty when the metric
+// is reported without unit.
+func (nrc *nrClient) FindMetricUnits(metricName, customTagKey, entityTag string, timeRange TimeRange) ([]string, error) {
+	query := fmt.Sprintf("SELECT uniques(unit) as 'units' from Metric where metricName = '%s' where %s = '%s'%s", metricName, customTagKey, entityTag, timeRange.clause())
+
+	a, err := nrc.client.Query(nrc.accountID, query)
+	if err != nil {
+		return nil, fmt.Errorf("executing query to fetch metric units %s, %w", query, err)
+	}
+	if len(a.Results) < 1 || a.Results[0]["units"] == nil {
+		return nil, nil
+	}
+
+	units, ok := a.Results[0]["units"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotValid, query)
+	}
+
+	var metricUnits []string
+	for _, u := range units {
+		metricUnits = append(metricUnits, fmt.Sprintf("%v", u))
+	}
+	return metricUnits, nil
+}
+
+// FindMetricTimeseries returns, for each entity reporting the metric, the number of its data points in each
+// bucket of the time range.
+func (nrc *nrClient) FindMetricTimeseries(metricName string, bucketSeconds int, customTagKey, entityTag string, timeRange TimeRange) (map[string][]float64, error) {
+	query := fmt.Sprintf(
+		"SELECT count(`%s`) as 'points' from Metric where metricName = '%s' where %s = '%s' FACET entity.guid LIMIT MAX%s TIMESERIES %d seconds",
+		metricName, metricName, customTagKey, entityTag, timeRange.clause(), bucketSeconds,
+	)
+
+	a, err := nrc.client.Query(nrc.accountID, query)
+	if err != nil {
+		return nil, fmt.Errorf("executing query to fetch metric timeseries %s, %w", query, err)
+	}
+	if len(a.Results) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoResult, query)
+	}
+
+	points, err := entityTimeseriesPoints(a.Results, "points")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, query)
+	}
+	return points, nil
+}
+
+func (nrc *nrClient) FindMetricValues(metricName, customTagKey, entityTag string, timeRange TimeRange) ([]MetricValues, error) {
+	query := fmt.Sprintf(
+		"SELECT min(`%s`) as 'min', max(`%s`) as 'max' from Metric where metricName = '%s' where %s = '%s' FACET entity.name limit MAX%s",
+		metricName, metricName, metricName, customTagKey, entityTag, timeRange.clause(),
+	)
+
+	a, err := nrc.client.Query(nrc.accountID, query)
+	if err != nil {
+		return nil, fmt.Errorf("executing query to fetch metric values %s, %w", query, err)
+	}
+	if len(a.Results) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoResult, query)
+	}
+
+	values := make([]MetricValues, 0, len(a.Results))
+	for _, r := range a.Results {
+		mv := MetricValues{Entity: fmt.Sprintf("%v", r["facet"])}
+		if minValue, err := extractFloat(r["min"]); err == nil {
+			mv.Min = &minValue
+		}
+		if maxValue, err := extractFloat(r["max"]); err == nil {
+			mv.Max = &maxValue
+		}
+		values = append(values, mv)
+	}
+	return values, nil
+}
+
+func (nrc *nrClient) NRQLQuery(query, customTagKey, entityTag string, timeRange TimeRange, errorExpected bool, expectedResults []spec.TestNRQLExpectedResult, timeseries *spec.TestNRQLTimeseries) error {
+	query = scenarioQuery(query, customTagKey, entityTag, timeRange)
+
+	a, err := nrc.client.Query(nrc.accountID, query)
+	if err != nil {
+		return fmt.Errorf("executing nrql query %s, %w", query, err)
+	}
+
+	return CheckNRQLResults(query, a.Results, errorExpected, expectedResults, timeseries)
+}
+
+// NRQLResults returns the results of the query filtered by the scenario, as NRQLQuery does.
+func (nrc *nrClient) NRQLResults(query, customTagKey, entityTag string, timeRange TimeRange) ([]nrdb.NRDBResult, error) {
+	query = scenarioQuery(query, customTagKey, entityTag, timeRange)
+
+	a, err := nrc.client.Query(nrc.accountID, query)
+	if err != nil {
+		return nil, fmt.Errorf("executing nrql query %s, %w", query, err)
+	}
+	return a.Results, nil
+}
+
+// scenarioQuery filters the query by the scenario tag and time range, and replaces the ${SCENARIO_TAG}
+// placeholder.
+func scenarioQuery(query, customTagKey, entityTag string, timeRange TimeRange) string {
+	query = fmt.Sprintf("%s WHERE %s = '%s'%s", query, customTagKey, entityTag, timeRange.queryClause(query))
+	return strings.ReplaceAll(query, "${SCENARIO_TAG}", entityTag)
+}
+
+func (nrc *nrClient) FindLogs(customTagKey, entityTag string, timeRange TimeRange) ([]nrdb.NRDBResult, error) {
+	query := fmt.Sprintf("SELECT * from Log where %s = '%s' limit MAX%s", customTagKey, entityTag, timeRange.clause())
+
+	a, err := nrc.client.Query(nrc.accountID, query)
+	if err != nil {
+		return nil, fmt.Errorf("executing query to fetch logs %s, %w", query, err)
+	}
+	return a.Results, nil
+}
+
+// FindRelatedEntities returns the relationships of the entity in both directions, following the
+// nextCursor of the relatedEntities field until all the pages are fetched.
+func (nrc *nrClient) FindRelatedEntities(guid common.EntityGUID) ([]Relationship, error) {
+	var relationships []Relationship
+	var cursor interface{}
+	for page := 0; page < maxRelatedEntitiesPages; page++ {
+		resp := relatedEntitiesResponse{}
+		variables := map[string]interface{}{"guid": guid, "cursor": cursor}
+		if err := nrc.client.NerdGraphQuery(relatedEntitiesQuery, variables, &resp); err != nil {
+			return nil, fmt.Errorf("executing query to fetch related entities of %s, %w", guid, err)
+		}
+
+		if resp.Actor.Entity == nil {
+			return nil, ErrNilEntity
+		}
+
+		for _, r := range resp.Actor.Entity.RelatedEntities.Results {
+			relationship := Relationship{
+				Type:       r.Type,
+				SourceGUID: r.Source.GUID,
+				TargetGUID: r.Target.GUID,
+			}
+			// The outline of the entities is missing when they are not accessible by the account.
+			if r.Source.Entity != nil {
+				relationship.SourceType = r.Source.Entity.Type
+				relationship.SourceName = r.Source.Entity.Name
+			}
+			if r.Target.Entity != nil {
+				relationship.TargetType = r.Target.Entity.Type
+				relationship.TargetName = r.Target.Entity.Name
+			}
+			relationships = append(relationships, relationship)
+		}
+
+		nextCursor := resp.Actor.Entity.RelatedEntities.NextCursor
+		if nextCursor == "" {
+			return relationships, nil
+		}
+		cursor = nextCursor
 	}
-	return nil
+	return nil, fmt.Errorf("fetching related entities of %s: more than %d pages", guid, maxRelatedEntitiesPages)
 }
 
 func resultMetrics(queryResults []nrdb.NRDBResult) []string {
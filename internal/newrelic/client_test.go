@@ -1,10 +1,12 @@
 package newrelic
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/newrelic/newrelic-client-go/pkg/common"
 	"github.com/newrelic/newrelic-client-go/pkg/entities"
@@ -15,6 +17,7 @@ const (
 	entityGUIDA           = "Mjc2Mjk0NXxJTkZSQXxOQXwtMzAzMjA2ODg0MjM5NDA1Nzg1OQ"
 	entityGUIDB           = "Axz2Mjk0NXxJTkZSQXxOQXwtMzAzMjA2ODg0MjM5NDA1Nzg1OQ"
 	sample                = "Metric"
+	legacySample          = "KafkaBrokerSample"
 	customTagKey          = "testKey"
 	entityTag             = "uuuuxxx"
 	errorMetricName       = "error-metric"
@@ -40,6 +43,11 @@ func (a apiClientMock) Query(_ int, query string) (*nrdb.NRDBResultContainer, er
 		sample, withoutGUIDMetricName, customTagKey, entityTag,
 	)
 
+	legacySampleQuery := fmt.Sprintf(
+		"SELECT uniques(entityGuid) from %s where %s = '%s' limit 1",
+		legacySample, customTagKey, entityTag,
+	)
+
 	switch query {
 	case errorQuery:
 		return nil, randomError
@@ -47,6 +55,14 @@ func (a apiClientMock) Query(_ int, query string) (*nrdb.NRDBResultContainer, er
 		return &nrdb.NRDBResultContainer{
 			Results: nil,
 		}, nil
+	case legacySampleQuery:
+		return &nrdb.NRDBResultContainer{
+			Results: []nrdb.NRDBResult{
+				map[string]interface{}{
+					"uniques.entityGuid": []interface{}{entityGUIDA},
+				},
+			},
+		}, nil
 	case withoutGUIDQuery:
 		return &nrdb.NRDBResultContainer{
 			Results: []nrdb.NRDBResult{
@@ -83,12 +99,56 @@ func (a apiClientMock) GetEntity(guid *common.EntityGUID) (*entities.EntityInter
 	return &entity, nil
 }
 
+const relatedEntitiesResult = `{
+  "actor": {
+    "entity": {
+      "relatedEntities": {
+        "results": [
+          {
+            "type": "CONTAINS",
+            "source": {"guid": "source-guid", "entity": {"type": "KAFKABROKER", "name": "broker-1"}},
+            "target": {"guid": "target-guid", "entity": {"type": "KAFKATOPIC", "name": "topic-1"}}
+          },
+          {
+            "type": "HOSTS",
+            "source": {"guid": "host-guid", "entity": null},
+            "target": {"guid": "source-guid", "entity": {"type": "KAFKABROKER", "name": "broker-1"}}
+          }
+        ]
+      }
+    }
+  }
+}`
+
+// relatedEntitiesPages are the pages returned for the paged-guid entity, by cursor.
+var relatedEntitiesPages = map[interface{}]string{
+	nil: `{"actor": {"entity": {"relatedEntities": {"nextCursor": "page-2", "results": [
+	  {"type": "CONTAINS", "source": {"guid": "paged-guid", "entity": {"type": "KAFKABROKER", "name": "broker-1"}}, "target": {"guid": "topic-1", "entity": {"type": "KAFKATOPIC", "name": "topic-1"}}}
+	]}}}}`,
+	"page-2": `{"actor": {"entity": {"relatedEntities": {"nextCursor": "", "results": [
+	  {"type": "CONTAINS", "source": {"guid": "paged-guid", "entity": {"type": "KAFKABROKER", "name": "broker-1"}}, "target": {"guid": "topic-2", "entity": {"type": "KAFKATOPIC", "name": "topic-2"}}}
+	]}}}}`,
+}
+
+func (a apiClientMock) NerdGraphQuery(_ string, variables map[string]interface{}, respBody interface{}) error {
+	switch variables["guid"] {
+	case common.EntityGUID(entityGUIDA):
+		return randomError
+	case common.EntityGUID(entityGUIDB):
+		return json.Unmarshal([]byte(`{"actor": {"entity": null}}`), respBody)
+	case common.EntityGUID("paged-guid"):
+		return json.Unmarshal([]byte(relatedEntitiesPages[variables["cursor"]]), respBody)
+	}
+	return json.Unmarshal([]byte(relatedEntitiesResult), respBody)
+}
+
 func TestNrClient_FindEntityGUIDs(t *testing.T) {
 	correctEntityA := common.EntityGUID(fmt.Sprintf("%+v", entityGUIDA))
 	correctEntityB := common.EntityGUID(fmt.Sprintf("%+v", entityGUIDB))
 
 	tests := []struct {
 		name           string
+		sample         string
 		metricName     string
 		entityGUIDs    []common.EntityGUID
 		expectedNumber int
@@ -124,6 +184,12 @@ func TestNrClient_FindEntityGUIDs(t *testing.T) {
 			expectedNumber: 2,
 			entityGUIDs:    []common.EntityGUID{correctEntityA, correctEntityB},
 		},
+		{
+			name:           "when the sample is a legacy event type it should return the entityGuid values",
+			sample:         legacySample,
+			expectedNumber: 1,
+			entityGUIDs:    []common.EntityGUID{correctEntityA},
+		},
 	}
 
 	for _, tt := range tests {
@@ -131,12 +197,96 @@ func TestNrClient_FindEntityGUIDs(t *testing.T) {
 			nrClient := nrClient{
 				client: apiClientMock{},
 			}
-			guid, err := nrClient.FindEntityGUIDs(sample, tt.metricName, customTagKey, entityTag, tt.expectedNumber)
+			testSample := sample
+			if tt.sample != "" {
+				testSample = tt.sample
+			}
+			guid, err := nrClient.FindEntityGUIDs(testSample, tt.metricName, "", customTagKey, entityTag, TimeRange{}, tt.expectedNumber)
 			if !errors.Is(err, tt.errorExpected) {
 				t.Errorf("Error expected: %v, error returned: %v", tt.errorExpected, err)
 			}
-			if guid != nil && !reflect.DeepEqual(guid, tt.entityGUIDs) {
-				t.Errorf("Expected: %v, got: %v", tt.entityGUIDs, tt.entityGUIDs)
+			if tt.entityGUIDs != nil && !reflect.DeepEqual(guid, tt.entityGUIDs) {
+				t.Errorf("Expected: %v, got: %v", tt.entityGUIDs, guid)
+			}
+		})
+	}
+}
+
+func Test_entityLookupQuery(t *testing.T) {
+	tests := []struct {
+		name          string
+		sample        string
+		metricName    string
+		attribute     string
+		queryExpected string
+	}{
+		{
+			name:          "when the sample is Metric it should look for entity.guid filtering by metric name",
+			sample:        "Metric",
+			metricName:    "powerdns_authoritative_up",
+			queryExpected: "SELECT uniques(entity.guid) from Metric where metricName = 'powerdns_authoritative_up' where testKey = 'e2e-tag' limit 1",
+		},
+		{
+			name:          "when the sample is an event type with a metric name it should look for entityGuid filtering by metric name",
+			sample:        "KafkaBrokerSample",
+			metricName:    "broker.IOInPerSecond",
+			queryExpected: "SELECT uniques(entityGuid) from KafkaBrokerSample where metricName = 'broker.IOInPerSecond' where testKey = 'e2e-tag' limit 1",
+		},
+		{
+			name:          "when the sample is an event type with an attribute it should look for entityGuid filtering by the attribute",
+			sample:        "KafkaBrokerSample",
+			attribute:     "broker.IOInPerSecond",
+			queryExpected: "SELECT uniques(entityGuid) from KafkaBrokerSample where `broker.IOInPerSecond` IS NOT NULL where testKey = 'e2e-tag' limit 1",
+		},
+		{
+			name:          "when the sample is an event type without attribute it should look for entityGuid without filter",
+			sample:        "KafkaBrokerSample",
+			queryExpected: "SELECT uniques(entityGuid) from KafkaBrokerSample where testKey = 'e2e-tag' limit 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if query := entityLookupQuery(tt.sample, tt.metricName, tt.attribute, "testKey", "e2e-tag", TimeRange{}); query != tt.queryExpected {
+				t.Errorf("Query returned %q, expected %q", query, tt.queryExpected)
+			}
+		})
+	}
+}
+
+func Test_scenarioQuery(t *testing.T) {
+	since := time.Date(2022, time.March, 1, 10, 0, 0, 0, time.UTC)
+	timeRange := TimeRange{Since: since, Until: since.Add(time.Minute)}
+
+	tests := []struct {
+		name          string
+		query         string
+		timeRange     TimeRange
+		queryExpected string
+	}{
+		{
+			name:          "when there is no time range it should only filter by the scenario tag",
+			query:         "SELECT count(*) FROM Metric WHERE tag = '${SCENARIO_TAG}'",
+			queryExpected: "SELECT count(*) FROM Metric WHERE tag = 'e2e-tag' WHERE testKey = 'e2e-tag'",
+		},
+		{
+			name:          "when there is a time range it should limit the query to it",
+			query:         "SELECT count(*) FROM Metric",
+			timeRange:     timeRange,
+			queryExpected: "SELECT count(*) FROM Metric WHERE testKey = 'e2e-tag' SINCE 1646128800000 UNTIL 1646128860000",
+		},
+		{
+			name:          "when the query has its own time clause it should not be limited to the time range",
+			query:         "SELECT count(*) FROM Metric since 15 minutes ago",
+			timeRange:     timeRange,
+			queryExpected: "SELECT count(*) FROM Metric since 15 minutes ago WHERE testKey = 'e2e-tag'",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if query := scenarioQuery(tt.query, "testKey", "e2e-tag", tt.timeRange); query != tt.queryExpected {
+				t.Errorf("Query returned %q, expected %q", query, tt.queryExpected)
 			}
 		})
 	}
@@ -189,3 +339,54 @@ func TestNrClient_FindEntityByGUID(t *testing.T) {
 		})
 	}
 }
+
+func TestNrClient_FindRelatedEntities(t *testing.T) {
+	tests := []struct {
+		name                  string
+		entityGUID            common.EntityGUID
+		relationshipsExpected []Relationship
+		errorExpected         error
+	}{
+		{
+			name:          "when the client call returns an error it should return it",
+			entityGUID:    entityGUIDA,
+			errorExpected: randomError,
+		},
+		{
+			name:          "when the entity is not found it should return ErrNilEntity",
+			entityGUID:    entityGUIDB,
+			errorExpected: ErrNilEntity,
+		},
+		{
+			name:       "when the entity has related entities it should return the relationships",
+			entityGUID: "source-guid",
+			relationshipsExpected: []Relationship{
+				{Type: "CONTAINS", SourceGUID: "source-guid", SourceType: "KAFKABROKER", SourceName: "broker-1", TargetGUID: "target-guid", TargetType: "KAFKATOPIC", TargetName: "topic-1"},
+				{Type: "HOSTS", SourceGUID: "host-guid", TargetGUID: "source-guid", TargetType: "KAFKABROKER", TargetName: "broker-1"},
+			},
+		},
+		{
+			name:       "when the related entities have several pages it should return the relationships of all of them",
+			entityGUID: "paged-guid",
+			relationshipsExpected: []Relationship{
+				{Type: "CONTAINS", SourceGUID: "paged-guid", SourceType: "KAFKABROKER", SourceName: "broker-1", TargetGUID: "topic-1", TargetType: "KAFKATOPIC", TargetName: "topic-1"},
+				{Type: "CONTAINS", SourceGUID: "paged-guid", SourceType: "KAFKABROKER", SourceName: "broker-1", TargetGUID: "topic-2", TargetType: "KAFKATOPIC", TargetName: "topic-2"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nrClient := nrClient{
+				client: apiClientMock{},
+			}
+			relationships, err := nrClient.FindRelatedEntities(tt.entityGUID)
+			if !errors.Is(err, tt.errorExpected) {
+				t.Errorf("Error returned is not: %v", tt.errorExpected)
+			}
+			if !reflect.DeepEqual(relationships, tt.relationshipsExpected) {
+				t.Errorf("Relationships returned %v, expected %v", relationships, tt.relationshipsExpected)
+			}
+		})
+	}
+}
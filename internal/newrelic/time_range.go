@@ -0,0 +1,38 @@
+package newrelic
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// timeClauseRegex finds the SINCE and UNTIL clauses of the queries of the spec file.
+var timeClauseRegex = regexp.MustCompile(`(?i)\b(SINCE|UNTIL)\b`)
+
+// TimeRange limits the queries to the data reported between Since and Until. A zero bound is left open,
+// so the zero TimeRange doesn't limit the queries.
+type TimeRange struct {
+	Since time.Time
+	Until time.Time
+}
+
+// clause returns the SINCE and UNTIL clauses of the range, as epoch milliseconds.
+func (tr TimeRange) clause() string {
+	var clause string
+	if !tr.Since.IsZero() {
+		clause += fmt.Sprintf(" SINCE %d", tr.Since.UnixMilli())
+	}
+	if !tr.Until.IsZero() {
+		clause += fmt.Sprintf(" UNTIL %d", tr.Until.UnixMilli())
+	}
+	return clause
+}
+
+// queryClause returns the clauses of the range for a query of the spec file, which is not limited when
+// it already has its own SINCE or UNTIL clause.
+func (tr TimeRange) queryClause(query string) string {
+	if timeClauseRegex.MatchString(query) {
+		return ""
+	}
+	return tr.clause()
+}
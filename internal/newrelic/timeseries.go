@@ -0,0 +1,158 @@
+package newrelic
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/newrelic/newrelic-client-go/pkg/nrdb"
+	"github.com/newrelic/newrelic-integration-e2e-action/internal/spec"
+)
+
+const (
+	timeseriesBeginKey = "beginTimeSeconds"
+	facetKey           = "facet"
+)
+
+// timeseriesBucket is a bucket of a TIMESERIES query. Value is nil for the buckets without data.
+type timeseriesBucket struct {
+	begin float64
+	value *float64
+}
+
+func nrqlQueryTimeseriesTest(queryResults []nrdb.NRDBResult, timeseries spec.TestNRQLTimeseries) error {
+	buckets, err := timeseriesBuckets(queryResults, timeseries.Key)
+	if err != nil {
+		return err
+	}
+
+	if len(buckets) <= timeseries.WarmupBuckets {
+		return fmt.Errorf("%w: got %d buckets, expected more than %d warm-up buckets", ErrResultNumber, len(buckets), timeseries.WarmupBuckets)
+	}
+	buckets = buckets[timeseries.WarmupBuckets:]
+
+	var failures []string
+	if timeseries.NonNull || timeseries.MaxGapBuckets != nil {
+		maxGap := 0
+		if timeseries.MaxGapBuckets != nil && !timeseries.NonNull {
+			maxGap = *timeseries.MaxGapBuckets
+		}
+		if gap, begin := longestGap(buckets); gap > maxGap {
+			failures = append(failures, fmt.Sprintf("got %d consecutive null buckets from %.0f, expected at most %d", gap, begin, maxGap))
+		}
+	}
+
+	var previous *timeseriesBucket
+	for i := range buckets {
+		current := buckets[i]
+		if current.value == nil {
+			continue
+		}
+		if previous != nil {
+			failures = append(failures, checkTimeseriesStep(*previous, current, timeseries)...)
+		}
+		previous = &current
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("%w: for key '%s': %s", ErrNotExpectedResult, timeseries.Key, strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// checkTimeseriesStep checks the monotonicity and rate between two consecutive non-null buckets.
+func checkTimeseriesStep(previous, current timeseriesBucket, timeseries spec.TestNRQLTimeseries) []string {
+	var failures []string
+	delta := *current.value - *previous.value
+
+	if timeseries.Monotonic && delta < 0 {
+		failures = append(failures, fmt.Sprintf("value decreased from %f to %f at %.0f", *previous.value, *current.value, current.begin))
+	}
+
+	if timeseries.MinRate == nil && timeseries.MaxRate == nil {
+		return failures
+	}
+	seconds := current.begin - previous.begin
+	if seconds <= 0 {
+		return append(failures, fmt.Sprintf("buckets are not sorted by %s at %.0f", timeseriesBeginKey, current.begin))
+	}
+	rate := delta / seconds
+	if err := checkBounds(rate, timeseries.MinRate, timeseries.MaxRate); err != nil {
+		failures = append(failures, fmt.Sprintf("rate per second at %.0f: %s", current.begin, err))
+	}
+	return failures
+}
+
+func timeseriesBuckets(queryResults []nrdb.NRDBResult, key string) ([]timeseriesBucket, error) {
+	buckets := make([]timeseriesBucket, 0, len(queryResults))
+	for _, row := range queryResults {
+		begin, err := extractFloat(row[timeseriesBeginKey])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s is missing, is it a TIMESERIES query?", ErrNotValid, timeseriesBeginKey)
+		}
+
+		bucket := timeseriesBucket{begin: begin}
+		if row[key] != nil {
+			value, err := extractFloat(row[key])
+			if err != nil {
+				return nil, fmt.Errorf("%w: for key '%s': %s", ErrNotValid, key, err)
+			}
+			bucket.value = &value
+		}
+		buckets = append(buckets, bucket)
+	}
+	return buckets, nil
+}
+
+// entityTimeseriesPoints returns the values of the key in the buckets of a TIMESERIES query faceted by
+// entity, by facet and sorted by the beginning of the buckets. Buckets without value count as 0.
+func entityTimeseriesPoints(queryResults []nrdb.NRDBResult, key string) (map[string][]float64, error) {
+	rowsByFacet := map[string][]nrdb.NRDBResult{}
+	for _, row := range queryResults {
+		facet, ok := row[facetKey].(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s is missing, is it a FACET query?", ErrNotValid, facetKey)
+		}
+		rowsByFacet[facet] = append(rowsByFacet[facet], row)
+	}
+
+	points := make(map[string][]float64, len(rowsByFacet))
+	for facet, rows := range rowsByFacet {
+		buckets, err := timeseriesBuckets(rows, key)
+		if err != nil {
+			return nil, err
+		}
+		sort.Slice(buckets, func(i, j int) bool { return buckets[i].begin < buckets[j].begin })
+
+		values := make([]float64, 0, len(buckets))
+		for _, bucket := range buckets {
+			if bucket.value == nil {
+				values = append(values, 0)
+				continue
+			}
+			values = append(values, *bucket.value)
+		}
+		points[facet] = values
+	}
+	return points, nil
+}
+
+// longestGap returns the longest run of null buckets and the beginning of its first bucket.
+func longestGap(buckets []timeseriesBucket) (int, float64) {
+	longest, current := 0, 0
+	var longestBegin, currentBegin float64
+	for _, bucket := range buckets {
+		if bucket.value != nil {
+			current = 0
+			continue
+		}
+		if current == 0 {
+			currentBegin = bucket.begin
+		}
+		current++
+		if current > longest {
+			longest, longestBegin = current, currentBegin
+		}
+	}
+	return longest, longestBegin
+}
@@ -0,0 +1,132 @@
+package newrelic
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/newrelic/newrelic-client-go/pkg/nrdb"
+	"github.com/newrelic/newrelic-integration-e2e-action/internal/spec"
+)
+
+func timeseriesResults(values ...any) []nrdb.NRDBResult {
+	results := make([]nrdb.NRDBResult, 0, len(values))
+	for i, value := range values {
+		results = append(results, nrdb.NRDBResult{
+			"beginTimeSeconds": float64(60 * i),
+			"endTimeSeconds":   float64(60 * (i + 1)),
+			"requests":         value,
+		})
+	}
+	return results
+}
+
+func Test_nrqlQueryTimeseriesTest(t *testing.T) {
+	minRate := 1.0
+	maxRate := 2.0
+	oneBucket := 1
+
+	tests := []struct {
+		name         string
+		queryResults []nrdb.NRDBResult
+		timeseries   spec.TestNRQLTimeseries
+		wantErr      bool
+	}{
+		{
+			name:         "when the query is not a timeseries it should return an error",
+			queryResults: []nrdb.NRDBResult{{"requests": 1.0}},
+			timeseries:   spec.TestNRQLTimeseries{Key: "requests", NonNull: true},
+			wantErr:      true,
+		},
+		{
+			name:         "when there are only warm-up buckets it should return an error",
+			queryResults: timeseriesResults(1.0, 2.0),
+			timeseries:   spec.TestNRQLTimeseries{Key: "requests", NonNull: true, WarmupBuckets: 2},
+			wantErr:      true,
+		},
+		{
+			name:         "when the null buckets are in the warm-up it should return no error",
+			queryResults: timeseriesResults(nil, nil, 1.0, 2.0),
+			timeseries:   spec.TestNRQLTimeseries{Key: "requests", NonNull: true, WarmupBuckets: 2},
+			wantErr:      false,
+		},
+		{
+			name:         "when there are null buckets after the warm-up it should return an error",
+			queryResults: timeseriesResults(nil, 1.0, nil, 2.0),
+			timeseries:   spec.TestNRQLTimeseries{Key: "requests", NonNull: true, WarmupBuckets: 1},
+			wantErr:      true,
+		},
+		{
+			name:         "when the gaps are not longer than max_gap_buckets it should return no error",
+			queryResults: timeseriesResults(1.0, nil, 2.0, nil, 3.0),
+			timeseries:   spec.TestNRQLTimeseries{Key: "requests", MaxGapBuckets: &oneBucket},
+			wantErr:      false,
+		},
+		{
+			name:         "when a gap is longer than max_gap_buckets it should return an error",
+			queryResults: timeseriesResults(1.0, nil, nil, 2.0),
+			timeseries:   spec.TestNRQLTimeseries{Key: "requests", MaxGapBuckets: &oneBucket},
+			wantErr:      true,
+		},
+		{
+			name:         "when the values increase ignoring gaps it should return no error",
+			queryResults: timeseriesResults(1.0, 1.0, nil, 5.0),
+			timeseries:   spec.TestNRQLTimeseries{Key: "requests", Monotonic: true},
+			wantErr:      false,
+		},
+		{
+			name:         "when a value decreases it should return an error",
+			queryResults: timeseriesResults(1.0, 5.0, 4.0),
+			timeseries:   spec.TestNRQLTimeseries{Key: "requests", Monotonic: true},
+			wantErr:      true,
+		},
+		{
+			name:         "when the rate is within bounds it should return no error",
+			queryResults: timeseriesResults(0.0, 60.0, 180.0),
+			timeseries:   spec.TestNRQLTimeseries{Key: "requests", MinRate: &minRate, MaxRate: &maxRate},
+			wantErr:      false,
+		},
+		{
+			name:         "when the rate is out of bounds it should return an error",
+			queryResults: timeseriesResults(0.0, 60.0, 300.0),
+			timeseries:   spec.TestNRQLTimeseries{Key: "requests", MinRate: &minRate, MaxRate: &maxRate},
+			wantErr:      true,
+		},
+		{
+			name:         "when a value is not numeric it should return an error",
+			queryResults: timeseriesResults(0.0, "up"),
+			timeseries:   spec.TestNRQLTimeseries{Key: "requests", Monotonic: true},
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := nrqlQueryTimeseriesTest(tt.queryResults, tt.timeseries); (err != nil) != tt.wantErr {
+				t.Errorf("nrqlQueryTimeseriesTest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_entityTimeseriesPoints(t *testing.T) {
+	results := []nrdb.NRDBResult{
+		{"facet": "guid-b", "beginTimeSeconds": 15.0, "points": 1.0},
+		{"facet": "guid-a", "beginTimeSeconds": 15.0, "points": nil},
+		{"facet": "guid-a", "beginTimeSeconds": 0.0, "points": 2.0},
+		{"facet": "guid-b", "beginTimeSeconds": 0.0, "points": 0.0},
+	}
+
+	points, err := entityTimeseriesPoints(results, "points")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string][]float64{"guid-a": {2, 0}, "guid-b": {0, 1}}
+	if !reflect.DeepEqual(expected, points) {
+		t.Errorf("expected %v, got %v", expected, points)
+	}
+
+	_, err = entityTimeseriesPoints(timeseriesResults(1.0), "requests")
+	if !errors.Is(err, ErrNotValid) {
+		t.Errorf("expected ErrNotValid for a query without facet, got %v", err)
+	}
+}
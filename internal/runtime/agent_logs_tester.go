@@ -0,0 +1,124 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/newrelic/newrelic-integration-e2e-action/internal/newrelic"
+	"github.com/newrelic/newrelic-integration-e2e-action/internal/spec"
+	"github.com/newrelic/newrelic-integration-e2e-action/pkg/retrier"
+	"github.com/sirupsen/logrus"
+)
+
+// maxReportedLogLines limits the matching lines shown in a failure, the whole logs can be printed
+// in verbose mode.
+const maxReportedLogLines = 5
+
+var ErrAgentNotEnabled = errors.New("agent_logs tests require the agent to be run by the e2e (agent_enabled)")
+
+// agentLogs is the part of the agent.Agent used by the tester.
+type agentLogs interface {
+	Logs(since, until time.Time) (string, error)
+}
+
+type AgentLogsTester struct {
+	agent  agentLogs
+	logger *logrus.Logger
+}
+
+// NewAgentLogsTester returns a tester of the logs of the given agent. The agent is nil when it is not
+// run by the e2e, failing the agent logs tests.
+func NewAgentLogsTester(agent agentLogs, logger *logrus.Logger) AgentLogsTester {
+	return AgentLogsTester{
+		agent:  agent,
+		logger: logger,
+	}
+}
+
+func (at AgentLogsTester) Test(tests spec.Tests, _, _ string, timeRange newrelic.TimeRange) []error {
+	if tests.AgentLogs == nil {
+		return nil
+	}
+
+	if at.agent == nil {
+		// Retrying cannot enable the agent.
+		return []error{retrier.Permanent(ErrAgentNotEnabled)}
+	}
+
+	logs, err := at.agent.Logs(timeRange.Since, timeRange.Until)
+	if err != nil {
+		return []error{fmt.Errorf("reading agent logs: %w", err)}
+	}
+
+	lines := strings.Split(logs, "\n")
+	at.logger.Debugf("found %d agent log lines", len(lines))
+
+	return checkAgentLogs(lines, *tests.AgentLogs)
+}
+
+// checkAgentLogs checks the lines of the agent logs. The lines matching fail_on are permanent errors, since
+// the retries cannot remove them from the logs, while the must_contain lines can still be written.
+func checkAgentLogs(lines []string, tal spec.TestAgentLogs) []error {
+	// The patterns have already been validated when parsing the spec file.
+	allowed := compileRegexes(tal.Allow)
+
+	var errors []error
+	for _, pattern := range tal.FailOnPatterns() {
+		failOn := regexp.MustCompile(pattern)
+
+		var matches []string
+		for _, line := range lines {
+			if failOn.MatchString(line) && !matchesAnyRegex(allowed, line) {
+				matches = append(matches, line)
+			}
+		}
+
+		if len(matches) > 0 {
+			errors = append(errors, retrier.Permanent(fmt.Errorf("found %d agent log lines matching %q:\n%s", len(matches), pattern, describeLogLines(matches))))
+		}
+	}
+
+	for _, pattern := range tal.MustContain {
+		if !matchesAnyLine(regexp.MustCompile(pattern), lines) {
+			errors = append(errors, fmt.Errorf("no agent log line matching %q", pattern))
+		}
+	}
+	return errors
+}
+
+func compileRegexes(patterns []string) []*regexp.Regexp {
+	regexes := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		regexes = append(regexes, regexp.MustCompile(pattern))
+	}
+	return regexes
+}
+
+func matchesAnyRegex(regexes []*regexp.Regexp, line string) bool {
+	for _, re := range regexes {
+		if re.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAnyLine(re *regexp.Regexp, lines []string) bool {
+	for _, line := range lines {
+		if re.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// describeLogLines returns the first matching lines, used to show them in failures.
+func describeLogLines(lines []string) string {
+	if len(lines) <= maxReportedLogLines {
+		return "  " + strings.Join(lines, "\n  ")
+	}
+	return fmt.Sprintf("  %s\n  ... and %d more", strings.Join(lines[:maxReportedLogLines], "\n  "), len(lines)-maxReportedLogLines)
+}
@@ -0,0 +1,158 @@
+package runtime
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/newrelic/newrelic-integration-e2e-action/internal/newrelic"
+	"github.com/newrelic/newrelic-integration-e2e-action/internal/spec"
+	"github.com/newrelic/newrelic-integration-e2e-action/pkg/retrier"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testAgentLogs = `time="2022-03-01T10:00:00Z" level=info msg="Integration health check starting" instance=nri-powerdns
+time="2022-03-01T10:00:01Z" level=error msg="can't connect to the inventory" component=inventory
+time="2022-03-01T10:00:02Z" level=warning msg="integration exited with error state" instance=nri-powerdns stderr="timeout reaching api"
+time="2022-03-01T10:00:03Z" level=info msg="Integration health check finished with success" instance=nri-powerdns`
+
+func TestAgentLogsTester_Test(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+	agentLogsTester := NewAgentLogsTester(&agentMock{AgentLogs: testAgentLogs}, log)
+
+	tests := []struct {
+		name                   string
+		agentLogs              *spec.TestAgentLogs
+		numberOfErrorsExpected int
+	}{
+		{
+			name:                   "when there is no agent logs test it should not return errors",
+			agentLogs:              nil,
+			numberOfErrorsExpected: 0,
+		},
+		{
+			name:                   "when the logs match the default patterns it should return one error per pattern",
+			agentLogs:              &spec.TestAgentLogs{},
+			numberOfErrorsExpected: 2,
+		},
+		{
+			name: "when the matching lines are allowed it should not return errors",
+			agentLogs: &spec.TestAgentLogs{
+				Allow: []string{"inventory", "timeout reaching api"},
+			},
+			numberOfErrorsExpected: 0,
+		},
+		{
+			name: "when the logs match the extra fail_on patterns it should return errors",
+			agentLogs: &spec.TestAgentLogs{
+				FailOn: []string{"level=warning"},
+				Allow:  []string{"inventory"},
+			},
+			numberOfErrorsExpected: 2,
+		},
+		{
+			name: "when the logs contain the must_contain patterns it should not return errors",
+			agentLogs: &spec.TestAgentLogs{
+				Allow:       []string{".*"},
+				MustContain: []string{"health check finished with success", "instance=nri-\\w+"},
+			},
+			numberOfErrorsExpected: 0,
+		},
+		{
+			name: "when the logs do not contain the must_contain patterns it should return one error per pattern",
+			agentLogs: &spec.TestAgentLogs{
+				Allow:       []string{".*"},
+				MustContain: []string{"instance=nri-mysql", "metrics submitted"},
+			},
+			numberOfErrorsExpected: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errors := agentLogsTester.Test(spec.Tests{AgentLogs: tt.agentLogs}, "testKey", "e2e-tag", newrelic.TimeRange{})
+			require.Equal(t, tt.numberOfErrorsExpected, len(errors))
+		})
+	}
+}
+
+func TestAgentLogsTester_Test_errorMessage(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+
+	agentLogsTester := NewAgentLogsTester(&agentMock{AgentLogs: testAgentLogs}, log)
+	errors := agentLogsTester.Test(spec.Tests{AgentLogs: &spec.TestAgentLogs{Allow: []string{"level=error"}}}, "testKey", "e2e-tag", newrelic.TimeRange{})
+	require.Equal(t, 1, len(errors))
+	assert.Contains(t, errors[0].Error(), `found 1 agent log lines matching "integration exited with error"`)
+	assert.Contains(t, errors[0].Error(), `stderr="timeout reaching api"`)
+
+	agentLogsTester = NewAgentLogsTester(nil, log)
+	errors = agentLogsTester.Test(spec.Tests{AgentLogs: &spec.TestAgentLogs{}}, "testKey", "e2e-tag", newrelic.TimeRange{})
+	require.Equal(t, 1, len(errors))
+	assert.ErrorIs(t, errors[0], ErrAgentNotEnabled)
+
+	err := retrier.Retry(log, 3, 0, func() []error {
+		return agentLogsTester.Test(spec.Tests{AgentLogs: &spec.TestAgentLogs{}}, "testKey", "e2e-tag", newrelic.TimeRange{})
+	})
+	assert.Contains(t, err.Error(), "after 1 attempts")
+}
+
+func TestAgentLogsTester_Test_logsError(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+
+	agentLogsTester := NewAgentLogsTester(&agentMock{LogsErr: ErrorTest}, log)
+	errors := agentLogsTester.Test(spec.Tests{AgentLogs: &spec.TestAgentLogs{}}, "testKey", "e2e-tag", newrelic.TimeRange{})
+	require.Equal(t, 1, len(errors))
+	assert.ErrorIs(t, errors[0], ErrorTest)
+}
+
+func TestAgentLogsTester_Test_failFast(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+
+	tests := []struct {
+		name              string
+		agentLogs         spec.TestAgentLogs
+		logsCallsExpected int
+	}{
+		{
+			name:              "when the logs match fail_on it should not retry the test",
+			agentLogs:         spec.TestAgentLogs{},
+			logsCallsExpected: 1,
+		},
+		{
+			name:              "when the logs do not contain must_contain it should retry the test",
+			agentLogs:         spec.TestAgentLogs{Allow: []string{".*"}, MustContain: []string{"metrics submitted"}},
+			logsCallsExpected: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agent := &agentMock{AgentLogs: testAgentLogs}
+			agentLogs := tt.agentLogs
+			runner := Runner{
+				agent:         agent,
+				testers:       []Tester{NewAgentLogsTester(agent, log)},
+				logger:        log,
+				spec:          &spec.Definition{Scenarios: []spec.Scenario{{Description: "scenario", Tests: spec.Tests{AgentLogs: &agentLogs}}}},
+				specParentDir: t.TempDir(),
+				retryAttempts: 3,
+				scenarioTag:   "e2e-tag",
+			}
+
+			require.Error(t, runner.Run())
+			assert.Equal(t, tt.logsCallsExpected, agent.LogsCalls)
+		})
+	}
+}
+
+func Test_describeLogLines(t *testing.T) {
+	lines := []string{"a", "b", "c", "d", "e", "f", "g"}
+
+	assert.Equal(t, "  a\n  b", describeLogLines(lines[:2]))
+	assert.Equal(t, "  a\n  b\n  c\n  d\n  e\n  ... and 2 more", describeLogLines(lines))
+}
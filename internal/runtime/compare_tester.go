@@ -0,0 +1,92 @@
+package runtime
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/newrelic/newrelic-integration-e2e-action/internal/newrelic"
+	"github.com/newrelic/newrelic-integration-e2e-action/internal/spec"
+	"github.com/sirupsen/logrus"
+)
+
+type CompareTester struct {
+	nrClient newrelic.Client
+	logger   *logrus.Logger
+}
+
+func NewCompareTester(nrClient newrelic.Client, logger *logrus.Logger) CompareTester {
+	return CompareTester{
+		nrClient: nrClient,
+		logger:   logger,
+	}
+}
+
+func (ct CompareTester) Test(tests spec.Tests, customTagKey, customTagValue string, timeRange newrelic.TimeRange) []error {
+	var errors []error
+	for _, tc := range tests.Compare {
+		values := map[string]float64{}
+		for _, cv := range tc.Values {
+			value, err := ct.queryValue(cv, customTagKey, customTagValue, timeRange)
+			if err != nil {
+				errors = append(errors, err)
+				continue
+			}
+			ct.logger.Debugf("compare value %s: %f", cv.Name, value)
+			values[cv.Name] = value
+		}
+
+		for _, relation := range tc.Relations {
+			left, okLeft := values[relation.Left]
+			right, okRight := values[relation.Right]
+			// The failure of the query has already been reported.
+			if !okLeft || !okRight {
+				continue
+			}
+			if err := checkRelation(left, right, relation); err != nil {
+				errors = append(errors, err)
+			}
+		}
+	}
+	return errors
+}
+
+func (ct CompareTester) queryValue(cv spec.CompareValue, customTagKey, customTagValue string, timeRange newrelic.TimeRange) (float64, error) {
+	results, err := ct.nrClient.NRQLResults(cv.Query, customTagKey, customTagValue, timeRange)
+	if err != nil {
+		return 0, fmt.Errorf("querying compare value %s: %w", cv.Name, err)
+	}
+	if len(results) == 0 {
+		return 0, fmt.Errorf("querying compare value %s: %w: %s", cv.Name, newrelic.ErrNoResult, cv.Query)
+	}
+
+	// NRDB results are decoded from JSON, so numbers are always float64.
+	value, ok := results[0][cv.Key].(float64)
+	if !ok {
+		return 0, fmt.Errorf("compare value %s: key %s is not a number, got '%v': %s", cv.Name, cv.Key, results[0][cv.Key], cv.Query)
+	}
+	return value, nil
+}
+
+func checkRelation(left, right float64, relation spec.CompareRelation) error {
+	switch relation.Relation {
+	case spec.RelationEqual:
+		if math.Abs(left-right) <= relation.Tolerance*math.Abs(right) {
+			return nil
+		}
+		return fmt.Errorf("%w - expected %s (%f) equal to %s (%f) with tolerance %g",
+			newrelic.ErrAssertionFailure, relation.Left, left, relation.Right, right, relation.Tolerance)
+	case spec.RelationRatio:
+		if right != 0 && math.Abs(left/right-relation.Ratio) <= relation.Tolerance*relation.Ratio {
+			return nil
+		}
+		return fmt.Errorf("%w - expected ratio of %s (%f) to %s (%f) to be %g with tolerance %g",
+			newrelic.ErrAssertionFailure, relation.Left, left, relation.Right, right, relation.Ratio, relation.Tolerance)
+	case spec.RelationLessThan:
+		if left < right {
+			return nil
+		}
+		return fmt.Errorf("%w - expected %s (%f) less than %s (%f)",
+			newrelic.ErrAssertionFailure, relation.Left, left, relation.Right, right)
+	}
+	return fmt.Errorf("unknown relation %q", relation.Relation)
+}
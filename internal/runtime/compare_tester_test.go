@@ -0,0 +1,80 @@
+package runtime
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/newrelic/newrelic-integration-e2e-action/internal/newrelic"
+	"github.com/newrelic/newrelic-integration-e2e-action/internal/spec"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareTester_Test(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+	compareTester := NewCompareTester(clientMock{}, log)
+
+	values := []spec.CompareValue{
+		{Name: "partitions", Query: "partitions bytes", Key: "value"},
+		{Name: "topic", Query: "topic bytes", Key: "value"},
+		{Name: "brokers", Query: "brokers", Key: "value"},
+		{Name: "entities", Query: "entities", Key: "value"},
+		{Name: "requests", Query: "requests", Key: "value"},
+	}
+
+	tests := []struct {
+		name                   string
+		compare                spec.TestCompare
+		numberOfErrorsExpected int
+	}{
+		{
+			name: "when the relations are satisfied it shouldn't return errors",
+			compare: spec.TestCompare{Values: values, Relations: []spec.CompareRelation{
+				{Left: "partitions", Right: "topic", Relation: spec.RelationEqual},
+				{Left: "entities", Right: "brokers", Relation: spec.RelationEqual},
+				{Left: "requests", Right: "topic", Relation: spec.RelationRatio, Ratio: 0.5},
+				{Left: "brokers", Right: "requests", Relation: spec.RelationLessThan},
+			}},
+			numberOfErrorsExpected: 0,
+		},
+		{
+			name: "when the values are equal within the tolerance it shouldn't return errors",
+			compare: spec.TestCompare{Values: values, Relations: []spec.CompareRelation{
+				{Left: "requests", Right: "topic", Relation: spec.RelationRatio, Ratio: 0.45, Tolerance: 0.2},
+			}},
+			numberOfErrorsExpected: 0,
+		},
+		{
+			name: "when the relations are not satisfied it should return an error for each one",
+			compare: spec.TestCompare{Values: values, Relations: []spec.CompareRelation{
+				{Left: "requests", Right: "topic", Relation: spec.RelationEqual, Tolerance: 0.1},
+				{Left: "requests", Right: "topic", Relation: spec.RelationRatio, Ratio: 2},
+				{Left: "topic", Right: "partitions", Relation: spec.RelationLessThan},
+			}},
+			numberOfErrorsExpected: 3,
+		},
+		{
+			name: "when a query fails or its value is not a number it should return an error without checking its relations",
+			compare: spec.TestCompare{
+				Values: []spec.CompareValue{
+					{Name: "failing", Query: errNRQLQuery, Key: "value"},
+					{Name: "state", Query: "state", Key: "value"},
+					{Name: "brokers", Query: "brokers", Key: "value"},
+				},
+				Relations: []spec.CompareRelation{
+					{Left: "failing", Right: "brokers", Relation: spec.RelationEqual},
+					{Left: "state", Right: "brokers", Relation: spec.RelationEqual},
+				},
+			},
+			numberOfErrorsExpected: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errors := compareTester.Test(spec.Tests{Compare: []spec.TestCompare{tt.compare}}, "", "", newrelic.TimeRange{})
+			assert.Equal(t, tt.numberOfErrorsExpected, len(errors))
+		})
+	}
+}
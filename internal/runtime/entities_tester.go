@@ -1,8 +1,11 @@
 package runtime
 
 import (
+	goerrors "errors"
 	"fmt"
+	"strings"
 
+	"github.com/newrelic/newrelic-client-go/pkg/entities"
 	"github.com/newrelic/newrelic-integration-e2e-action/internal/newrelic"
 	"github.com/newrelic/newrelic-integration-e2e-action/internal/spec"
 	"github.com/sirupsen/logrus"
@@ -20,31 +23,140 @@ func NewEntitiesTester(nrClient newrelic.Client, logger *logrus.Logger) Entities
 	}
 }
 
-func (et EntitiesTester) Test(tests spec.Tests, customTagKey, customTagValue string) []error {
+func (et EntitiesTester) Test(tests spec.Tests, customTagKey, customTagValue string, timeRange newrelic.TimeRange) []error {
 	var errors []error
 	for _, en := range tests.Entities {
-		// By default if not notified, we set expectedNumber to 1
-		if en.ExpectedNumber == 0 {
-			en.ExpectedNumber = 1
-		}
-		guids, err := et.nrClient.FindEntityGUIDs(en.DataType, en.MetricName, customTagKey, customTagValue, en.ExpectedNumber)
-		if err != nil {
+		minCount, maxCount := en.CountBounds()
+
+		// The number of entities is checked below to list the entities found on failure.
+		guids, err := et.nrClient.FindEntityGUIDs(en.DataType, en.MetricName, en.Attribute, customTagKey, customTagValue, timeRange, 0)
+		if err != nil && !(goerrors.Is(err, newrelic.ErrNoResult) && minCount == 0) {
 			errors = append(errors, fmt.Errorf("finding entity guid: %w", err))
 			continue
 		}
+
+		// Only the entities resolved with the type of the test are counted, since the same sample or
+		// metric can be reported by entities of other types. Entity GUIDs without entity (i.e. from sample
+		// shimming) are not counted either, and both are listed in the count failures.
+		var found, ignored []string
+		lookupFailed := false
 		for _, guid := range guids {
 			entity, err := et.nrClient.FindEntityByGUID(&guid)
 			if err != nil {
 				errors = append(errors, fmt.Errorf("finding entity guid: %w", err))
+				lookupFailed = true
 				continue
 			}
 
-			// Some entity GUIDs (from sample shimming) don't return any object, if it's the case we don't fail the test
-			if entity != nil && entity.GetType() != en.Type {
-				errors = append(errors, fmt.Errorf("entity type is not matching: %s!=%s", entity.GetType(), en.Type))
+			if entity == nil {
+				ignored = append(ignored, fmt.Sprintf("%s (not found)", guid))
 				continue
 			}
+			if entity.GetType() != en.Type {
+				ignored = append(ignored, fmt.Sprintf("%s (%s of type %s)", guid, entity.GetName(), entity.GetType()))
+				continue
+			}
+			found = append(found, fmt.Sprintf("%s (%s)", guid, entity.GetName()))
+			if !en.Absent {
+				errors = append(errors, et.checkAttributes(entity, en)...)
+			}
+		}
+
+		// The entities that could not be looked up already failed the test and cannot be counted.
+		if lookupFailed {
+			continue
+		}
+		if en.Absent && len(found) > 0 {
+			errors = append(errors, fmt.Errorf("entities of type %s were expected to be absent, found: %s", en.Type, strings.Join(found, ", ")))
+			continue
+		}
+		if len(found) < minCount || (maxCount > 0 && len(found) > maxCount) {
+			errors = append(errors, fmt.Errorf("%w: entities of type %s: got %d, expected %s, found: [%s], ignored: [%s]",
+				newrelic.ErrResultNumber, en.Type, len(found), describeCountBounds(minCount, maxCount), strings.Join(found, ", "), strings.Join(ignored, ", ")))
+		}
+	}
+	return errors
+}
+
+func describeCountBounds(minCount, maxCount int) string {
+	switch {
+	case minCount == maxCount:
+		return fmt.Sprintf("exactly %d", minCount)
+	case maxCount == 0:
+		return fmt.Sprintf("at least %d", minCount)
+	default:
+		return fmt.Sprintf("between %d and %d", minCount, maxCount)
+	}
+}
+
+// reportingEntity and goldenMetricsEntity are implemented by the entity types that expose the
+// reporting status and golden metrics, which are not part of entities.EntityInterface.
+type reportingEntity interface {
+	GetReporting() bool
+}
+
+type goldenMetricsEntity interface {
+	GetGoldenMetrics() entities.EntityGoldenContextScopedGoldenMetrics
+}
+
+// checkAttributes compares the name, tags and attributes of the entity with the ones defined in the test.
+func (et EntitiesTester) checkAttributes(entity entities.EntityInterface, en spec.TestEntity) []error {
+	var errors []error
+
+	if en.NamePattern != "" && !spec.MatchesPattern(en.NamePattern, entity.GetName()) {
+		errors = append(errors, fmt.Errorf("entity %s name is not matching %q", entity.GetName(), en.NamePattern))
+	}
+
+	tags := map[string][]string{}
+	for _, tag := range entity.GetTags() {
+		tags[tag.Key] = tag.Values
+	}
+	for key, pattern := range en.Tags {
+		if !matchesAnyValue(pattern, tags[key]) {
+			errors = append(errors, fmt.Errorf("entity %s tag %s is not matching %q: got %v", entity.GetName(), key, pattern, tags[key]))
+		}
+	}
+
+	if en.Attributes == nil {
+		return errors
+	}
+
+	if en.Attributes.Domain != "" && entity.GetDomain() != en.Attributes.Domain {
+		errors = append(errors, fmt.Errorf("entity %s domain is not matching: %s!=%s", entity.GetName(), entity.GetDomain(), en.Attributes.Domain))
+	}
+
+	if en.Attributes.Reporting != nil {
+		re, ok := entity.(reportingEntity)
+		if !ok {
+			errors = append(errors, fmt.Errorf("entity %s does not expose its reporting status", entity.GetName()))
+		} else if re.GetReporting() != *en.Attributes.Reporting {
+			errors = append(errors, fmt.Errorf("entity %s reporting is not matching: %t!=%t", entity.GetName(), re.GetReporting(), *en.Attributes.Reporting))
+		}
+	}
+
+	if len(en.Attributes.GoldenMetrics) > 0 {
+		gme, ok := entity.(goldenMetricsEntity)
+		if !ok {
+			return append(errors, fmt.Errorf("entity %s does not expose golden metrics", entity.GetName()))
+		}
+		goldenMetrics := map[string]bool{}
+		for _, gm := range gme.GetGoldenMetrics().Metrics {
+			goldenMetrics[gm.Name] = true
+		}
+		for _, name := range en.Attributes.GoldenMetrics {
+			if !goldenMetrics[name] {
+				errors = append(errors, fmt.Errorf("entity %s golden metric not found: %s", entity.GetName(), name))
+			}
 		}
 	}
 	return errors
 }
+
+func matchesAnyValue(pattern string, values []string) bool {
+	for _, value := range values {
+		if spec.MatchesPattern(pattern, value) {
+			return true
+		}
+	}
+	return false
+}
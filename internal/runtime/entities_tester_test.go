@@ -4,9 +4,11 @@ import (
 	"io/ioutil"
 	"testing"
 
+	"github.com/newrelic/newrelic-integration-e2e-action/internal/newrelic"
 	"github.com/newrelic/newrelic-integration-e2e-action/internal/spec"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestEntitiesTester_Test(t *testing.T) {
@@ -37,6 +39,158 @@ func TestEntitiesTester_Test(t *testing.T) {
 		},
 	}}
 
-	errors := entitiesTester.Test(inputTests, "", "")
+	errors := entitiesTester.Test(inputTests, "", "", newrelic.TimeRange{})
 	assert.Equal(t, 3, len(errors))
 }
+
+func TestEntitiesTester_Test_ignoredEntities(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+	entitiesTester := NewEntitiesTester(clientMock{}, log)
+
+	// The entities of other types and the GUIDs without entity only fail the count, listed as ignored.
+	errors := entitiesTester.Test(spec.Tests{Entities: []spec.TestEntity{{Type: otherEntityType, DataType: nilEntityGUID}}}, "", "", newrelic.TimeRange{})
+	require.Equal(t, 1, len(errors))
+	assert.ErrorIs(t, errors[0], newrelic.ErrResultNumber)
+	assert.Contains(t, errors[0].Error(), "ignored: [AAAA (localhost:8081 of type correctEntityType), nilEntitySample (not found)]")
+}
+
+func TestEntitiesTester_checkAttributes(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+	entitiesTester := NewEntitiesTester(clientMock{}, log)
+
+	reporting := true
+	notReporting := false
+	tests := []struct {
+		name                   string
+		testEntity             spec.TestEntity
+		numberOfErrorsExpected int
+	}{
+		{
+			name:                   "when the test doesn't define attributes it shouldn't return errors",
+			testEntity:             spec.TestEntity{Type: correctEntityType},
+			numberOfErrorsExpected: 0,
+		},
+		{
+			name: "when the entity matches every assertion it shouldn't return errors",
+			testEntity: spec.TestEntity{
+				Type:        correctEntityType,
+				NamePattern: "*:8081",
+				Tags:        map[string]string{"clusterName": "regex:e2e-.+"},
+				Attributes:  &spec.EntityAttributes{Domain: "INFRA", Reporting: &reporting, GoldenMetrics: []string{"queries"}},
+			},
+			numberOfErrorsExpected: 0,
+		},
+		{
+			name:                   "when the name is not matching it should return an error",
+			testEntity:             spec.TestEntity{Type: correctEntityType, NamePattern: "regex:[a-z]+"},
+			numberOfErrorsExpected: 1,
+		},
+		{
+			name:                   "when a tag is missing or not matching it should return an error for each one",
+			testEntity:             spec.TestEntity{Type: correctEntityType, Tags: map[string]string{"clusterName": "prod-*", "namespace": "*"}},
+			numberOfErrorsExpected: 2,
+		},
+		{
+			name: "when the attributes are not matching it should return an error for each one",
+			testEntity: spec.TestEntity{
+				Type:       correctEntityType,
+				Attributes: &spec.EntityAttributes{Domain: "APM", Reporting: &notReporting, GoldenMetrics: []string{"queries", "latency"}},
+			},
+			numberOfErrorsExpected: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errors := entitiesTester.Test(spec.Tests{Entities: []spec.TestEntity{tt.testEntity}}, "", "", newrelic.TimeRange{})
+			assert.Equal(t, tt.numberOfErrorsExpected, len(errors))
+		})
+	}
+}
+
+func TestEntitiesTester_counts(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+	entitiesTester := NewEntitiesTester(clientMock{}, log)
+
+	tests := []struct {
+		name                   string
+		testEntity             spec.TestEntity
+		numberOfErrorsExpected int
+	}{
+		{
+			name:                   "when the entities found are within min and max it shouldn't return errors",
+			testEntity:             spec.TestEntity{Type: correctEntityType, DataType: duplicatedEntityGUID, Min: 1, Max: 2},
+			numberOfErrorsExpected: 0,
+		},
+		{
+			name:                   "when the entities found are the exact number it shouldn't return errors",
+			testEntity:             spec.TestEntity{Type: correctEntityType, DataType: duplicatedEntityGUID, Exact: 2},
+			numberOfErrorsExpected: 0,
+		},
+		{
+			name:                   "when there are duplicated entities it should return an error",
+			testEntity:             spec.TestEntity{Type: correctEntityType, DataType: duplicatedEntityGUID, Exact: 1},
+			numberOfErrorsExpected: 1,
+		},
+		{
+			name:                   "when there are more entities than max it should return an error",
+			testEntity:             spec.TestEntity{Type: correctEntityType, DataType: duplicatedEntityGUID, Max: 1},
+			numberOfErrorsExpected: 1,
+		},
+		{
+			name:                   "when there are less entities than expected_number it should return an error",
+			testEntity:             spec.TestEntity{Type: correctEntityType, DataType: duplicatedEntityGUID, ExpectedNumber: 3},
+			numberOfErrorsExpected: 1,
+		},
+		{
+			name:                   "when no entity is found and it is expected to be absent it shouldn't return errors",
+			testEntity:             spec.TestEntity{Type: correctEntityType, DataType: noEntityGUID, Absent: true},
+			numberOfErrorsExpected: 0,
+		},
+		{
+			name:                   "when only entities of other types are found and it is expected to be absent it shouldn't return errors",
+			testEntity:             spec.TestEntity{Type: otherEntityType, Absent: true},
+			numberOfErrorsExpected: 0,
+		},
+		{
+			name:                   "when an entity is found and it is expected to be absent it should return an error",
+			testEntity:             spec.TestEntity{Type: correctEntityType, DataType: duplicatedEntityGUID, Absent: true},
+			numberOfErrorsExpected: 1,
+		},
+		{
+			name:                   "when an entity guid doesn't resolve to an entity it should not be counted",
+			testEntity:             spec.TestEntity{Type: correctEntityType, DataType: nilEntityGUID, Exact: 2},
+			numberOfErrorsExpected: 1,
+		},
+		{
+			name:                   "when only unresolved entities are found and it is expected to be absent it shouldn't return errors",
+			testEntity:             spec.TestEntity{Type: otherEntityType, DataType: nilEntityGUID, Absent: true},
+			numberOfErrorsExpected: 0,
+		},
+		{
+			name:                   "when an entity of another type is found it should not be counted",
+			testEntity:             spec.TestEntity{Type: correctEntityType, DataType: otherTypeEntityGUID, Max: 1},
+			numberOfErrorsExpected: 0,
+		},
+		{
+			name:                   "when an entity of another type is found the exact count should only include the type of the test",
+			testEntity:             spec.TestEntity{Type: correctEntityType, DataType: otherTypeEntityGUID, Exact: 2},
+			numberOfErrorsExpected: 1,
+		},
+		{
+			name:                   "when no entity is found and it is not expected to be absent it should return an error",
+			testEntity:             spec.TestEntity{Type: correctEntityType, DataType: noEntityGUID},
+			numberOfErrorsExpected: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errors := entitiesTester.Test(spec.Tests{Entities: []spec.TestEntity{tt.testEntity}}, "", "", newrelic.TimeRange{})
+			assert.Equal(t, tt.numberOfErrorsExpected, len(errors))
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package runtime
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/newrelic/newrelic-integration-e2e-action/internal/newrelic"
+	"github.com/newrelic/newrelic-integration-e2e-action/internal/spec"
+	"github.com/sirupsen/logrus"
+)
+
+const scrapeTimeout = 10 * time.Second
+
+// ExportersTester scrapes the Prometheus exporters of the scenario, so the metrics missing at the exporter
+// are reported separately from the ones scraped but not found in New Relic.
+type ExportersTester struct {
+	nrClient      newrelic.Client
+	logger        *logrus.Logger
+	specParentDir string
+	httpClient    *http.Client
+}
+
+func NewExportersTester(nrClient newrelic.Client, logger *logrus.Logger, specParentDir string) ExportersTester {
+	return ExportersTester{
+		nrClient:      nrClient,
+		logger:        logger,
+		specParentDir: specParentDir,
+		httpClient:    &http.Client{Timeout: scrapeTimeout},
+	}
+}
+
+func (et ExportersTester) Test(tests spec.Tests, customTagKey, customTagValue string, timeRange newrelic.TimeRange) []error {
+	var errors []error
+	for _, te := range tests.Exporters {
+		scraped, err := et.scrape(te.URL)
+		if err != nil {
+			errors = append(errors, fmt.Errorf("scraping exporter %s: %w", te.URL, err))
+			continue
+		}
+		et.logger.Debugf("scraped %d series from exporter %s", len(scraped.Series), te.URL)
+
+		var missing []string
+		for _, pattern := range te.Metrics {
+			if !scraped.Matches(pattern) {
+				missing = append(missing, pattern)
+			}
+		}
+
+		var exposed []string
+		if te.Source != "" {
+			content, err := ioutil.ReadFile(filepath.Join(et.specParentDir, te.Source))
+			if err != nil {
+				errors = append(errors, fmt.Errorf("reading metrics source file: %w", err))
+				continue
+			}
+			metrics, err := spec.ParseMetricsFile(content)
+			if err != nil {
+				errors = append(errors, fmt.Errorf("unmarshaling metrics source file: %w", err))
+				continue
+			}
+
+			for _, entity := range metrics.Entities {
+				if matchesAnyException(entity.EntityType, te.ExceptEntities) {
+					continue
+				}
+				for _, metric := range entity.Metrics {
+					if matchesAnyException(metric.Name, te.ExceptMetrics) {
+						continue
+					}
+					// Families like histograms are stored in New Relic by series, i.e. `_bucket`, `_sum` and `_count`.
+					series := scraped.SeriesOf(metric.Name)
+					if len(series) == 0 {
+						missing = append(missing, metric.Name)
+						continue
+					}
+					exposed = append(exposed, series...)
+				}
+			}
+		}
+
+		if len(missing) > 0 {
+			errors = append(errors, fmt.Errorf("metrics missing at the exporter %s: %s", te.URL, strings.Join(missing, ", ")))
+		}
+
+		if len(exposed) == 0 {
+			continue
+		}
+
+		reported, err := et.nrClient.FindMetricNames(customTagKey, customTagValue, timeRange)
+		if err != nil {
+			errors = append(errors, fmt.Errorf("finding metric names: %w", err))
+			continue
+		}
+
+		var lost []string
+		for _, name := range exposed {
+			if !containsString(reported, name) {
+				lost = append(lost, name)
+			}
+		}
+		if len(lost) > 0 {
+			errors = append(errors, fmt.Errorf("metrics exposed by the exporter %s but not found in New Relic: %s", te.URL, strings.Join(lost, ", ")))
+		}
+	}
+	return errors
+}
+
+func (et ExportersTester) scrape(url string) (scrapedMetrics, error) {
+	resp, err := et.httpClient.Get(url)
+	if err != nil {
+		return scrapedMetrics{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return scrapedMetrics{}, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	return parsePrometheusText(resp.Body)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
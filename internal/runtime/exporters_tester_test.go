@@ -0,0 +1,86 @@
+package runtime
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/newrelic/newrelic-integration-e2e-action/internal/newrelic"
+	"github.com/newrelic/newrelic-integration-e2e-action/internal/spec"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportersTester_Test(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+	exportersTester := NewExportersTester(clientMock{}, log, "testdata")
+
+	exporter := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/metrics":
+			_, _ = w.Write([]byte(testExporterMetrics))
+		case "/malformed":
+			_, _ = w.Write([]byte("powerdns_authoritative_up"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer exporter.Close()
+
+	tests := []struct {
+		name           string
+		exporter       spec.TestExporter
+		errorsExpected []string
+	}{
+		{
+			name:     "when the metrics are exposed it should not return errors",
+			exporter: spec.TestExporter{URL: exporter.URL + "/metrics", Metrics: []string{"powerdns_authoritative_latency_seconds", "powerdns_authoritative_*"}},
+		},
+		{
+			name:     "when the metrics of the source are exposed and reported it should not return errors",
+			exporter: spec.TestExporter{URL: exporter.URL + "/metrics", Source: "exporter.yml", Exceptions: spec.Exceptions{ExceptMetrics: []spec.Exception{{Name: "powerdns_authoritative_queries_total"}, {Name: "powerdns_authoritative_up"}}}},
+		},
+		{
+			name:     "when metrics are missing at the exporter or in New Relic it should report them separately",
+			exporter: spec.TestExporter{URL: exporter.URL + "/metrics", Source: "exporter.yml", Metrics: []string{"powerdns_recursor_*"}},
+			errorsExpected: []string{
+				"metrics missing at the exporter " + exporter.URL + "/metrics: powerdns_recursor_*, powerdns_authoritative_up",
+				"metrics exposed by the exporter " + exporter.URL + "/metrics but not found in New Relic: powerdns_authoritative_queries_total",
+			},
+		},
+		{
+			name:     "when the series of the histogram and summary families are reported it should not return errors",
+			exporter: spec.TestExporter{URL: exporter.URL + "/metrics", Source: "exporter_families.yml", Exceptions: spec.Exceptions{ExceptMetrics: []spec.Exception{{Name: "powerdns_authoritative_response_seconds"}}}},
+		},
+		{
+			name:     "when a series of a family is not reported it should report it as lost",
+			exporter: spec.TestExporter{URL: exporter.URL + "/metrics", Source: "exporter_families.yml"},
+			errorsExpected: []string{
+				"metrics exposed by the exporter " + exporter.URL + "/metrics but not found in New Relic: powerdns_authoritative_response_seconds_count",
+			},
+		},
+		{
+			name:           "when the exporter does not expose metrics it should return an error",
+			exporter:       spec.TestExporter{URL: exporter.URL + "/not-found", Metrics: []string{"*"}},
+			errorsExpected: []string{"unexpected status code 404"},
+		},
+		{
+			name:           "when the exporter exposes malformed metrics it should return an error",
+			exporter:       spec.TestExporter{URL: exporter.URL + "/malformed", Metrics: []string{"*"}},
+			errorsExpected: []string{"line 1: missing value"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errors := exportersTester.Test(spec.Tests{Exporters: []spec.TestExporter{tt.exporter}}, "testKey", "e2e-tag", newrelic.TimeRange{})
+			require.Equal(t, len(tt.errorsExpected), len(errors), "errors: %v", errors)
+			for i, expected := range tt.errorsExpected {
+				assert.Contains(t, errors[i].Error(), expected)
+			}
+		})
+	}
+}
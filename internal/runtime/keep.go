@@ -0,0 +1,166 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/newrelic/newrelic-integration-e2e-action/internal/agent"
+	"github.com/newrelic/newrelic-integration-e2e-action/internal/spec"
+	"github.com/sirupsen/logrus"
+	yaml "gopkg.in/yaml.v3"
+)
+
+const keptScenarioFileExt = ".yml"
+
+// keptScenariosDir is where the state of the scenarios kept alive after a failure is stored.
+var keptScenariosDir = filepath.Join(os.TempDir(), "newrelic-integration-e2e")
+
+var ErrNoKeptScenario = errors.New("no kept scenario found")
+
+// KeptScenario holds everything needed to tear down a failed scenario that was not cleaned up
+// because the runner was executed with keep_on_failure.
+type KeptScenario struct {
+	ScenarioTag   string             `yaml:"scenario_tag"`
+	CustomTestKey string             `yaml:"custom_test_key"`
+	SpecParentDir string             `yaml:"spec_parent_dir"`
+	PlainLogs     bool               `yaml:"plain_logs"`
+	After         []string           `yaml:"after"`
+	Agent         *agent.Environment `yaml:"agent,omitempty"`
+}
+
+func (r *Runner) keepScenario(scenario spec.Scenario, scenarioTag string, scenarioErr error) error {
+	kept := KeptScenario{
+		ScenarioTag:   scenarioTag,
+		CustomTestKey: r.spec.CustomTestKey,
+		SpecParentDir: r.specParentDir,
+		PlainLogs:     r.spec.PlainLogs,
+		After:         scenario.After,
+	}
+	if r.agent != nil {
+		env := r.agent.Environment()
+		kept.Agent = &env
+	}
+
+	if err := saveKeptScenario(r.keptDir, kept); err != nil {
+		r.logger.Errorf("saving kept scenario, the environment must be cleaned up manually: %v", err)
+	}
+
+	r.logger.Warn(kept.debugInfo())
+
+	return scenarioErr
+}
+
+// debugInfo returns a human-readable summary of the kept environment with commands to inspect it.
+func (k KeptScenario) debugInfo() string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "scenario failed, its environment has been kept for debugging\n")
+	fmt.Fprintf(&sb, "  scenario tag: %s\n", k.ScenarioTag)
+
+	if k.Agent != nil {
+		var envPrefix strings.Builder
+		envVars := k.Agent.EnvVars()
+		keys := make([]string, 0, len(envVars))
+		for key := range envVars {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(&envPrefix, "%s=%s ", key, envVars[key])
+		}
+		compose := fmt.Sprintf("%sdocker compose -f %s", envPrefix.String(), k.Agent.DockerComposePath)
+
+		fmt.Fprintf(&sb, "  compose file: %s\n", k.Agent.DockerComposePath)
+		fmt.Fprintf(&sb, "  container: %s\n", k.Agent.ContainerName)
+		fmt.Fprintf(&sb, "  configs dir: %s\n", k.Agent.ConfigsDir)
+		fmt.Fprintf(&sb, "  bins dir: %s\n", k.Agent.BinsDir)
+		fmt.Fprintf(&sb, "  exporters dir: %s\n", k.Agent.ExportersDir)
+		fmt.Fprintf(&sb, "  agent logs: %s logs %s\n", compose, k.Agent.ContainerName)
+		fmt.Fprintf(&sb, "  agent shell: %s exec %s bash\n", compose, k.Agent.ContainerName)
+	}
+
+	fmt.Fprintf(&sb, "  metrics: SELECT uniques(metricName) FROM Metric WHERE %s = '%s' SINCE 1 hour ago\n", k.CustomTestKey, k.ScenarioTag)
+	fmt.Fprintf(&sb, "  data points: SELECT * FROM Metric WHERE %s = '%s' SINCE 1 hour ago LIMIT 100\n", k.CustomTestKey, k.ScenarioTag)
+	fmt.Fprintf(&sb, "  cleanup: newrelic-integration-e2e-action cleanup --scenario_tag=%s", k.ScenarioTag)
+
+	return sb.String()
+}
+
+func saveKeptScenario(dir string, kept KeptScenario) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("creating kept scenarios dir: %w", err)
+	}
+
+	content, err := yaml.Marshal(kept)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filepath.Join(dir, kept.ScenarioTag+keptScenarioFileExt), content, 0644)
+}
+
+func loadKeptScenarios(dir string, scenarioTag string) ([]KeptScenario, error) {
+	pattern := "*" + keptScenarioFileExt
+	if scenarioTag != "" {
+		pattern = scenarioTag + keptScenarioFileExt
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, pattern))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, ErrNoKeptScenario
+	}
+
+	kept := make([]KeptScenario, 0, len(paths))
+	for _, path := range paths {
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading kept scenario %s: %w", path, err)
+		}
+		k := KeptScenario{}
+		if err := yaml.Unmarshal(content, &k); err != nil {
+			return nil, fmt.Errorf("parsing kept scenario %s: %w", path, err)
+		}
+		kept = append(kept, k)
+	}
+	return kept, nil
+}
+
+// Cleanup tears down the environments kept by previous failed runs: it executes the `after` commands
+// of the scenario and stops the agent. If scenarioTag is empty every kept scenario is cleaned up.
+func Cleanup(logger *logrus.Logger, scenarioTag string) error {
+	return cleanup(logger, keptScenariosDir, scenarioTag)
+}
+
+func cleanup(logger *logrus.Logger, dir string, scenarioTag string) error {
+	keptScenarios, err := loadKeptScenarios(dir, scenarioTag)
+	if err != nil {
+		return err
+	}
+
+	for _, kept := range keptScenarios {
+		logger.Infof("cleaning up scenario %s", kept.ScenarioTag)
+
+		if err := executeOSCommands(logger, kept.PlainLogs, kept.SpecParentDir, kept.After, kept.ScenarioTag); err != nil {
+			logger.Error(err)
+		}
+
+		if kept.Agent != nil {
+			if err := agent.Teardown(*kept.Agent); err != nil {
+				return fmt.Errorf("tearing down agent of scenario %s: %w", kept.ScenarioTag, err)
+			}
+		}
+
+		if err := os.Remove(filepath.Join(dir, kept.ScenarioTag+keptScenarioFileExt)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package runtime
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCleanup(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+
+	keptDir := t.TempDir()
+	specParentDir := t.TempDir()
+
+	kept := KeptScenario{
+		ScenarioTag:   "e2e-tag",
+		CustomTestKey: "testKey",
+		SpecParentDir: specParentDir,
+		PlainLogs:     true,
+		After:         []string{"echo $SCENARIO_TAG > after.txt"},
+	}
+	require.NoError(t, saveKeptScenario(keptDir, kept))
+
+	loaded, err := loadKeptScenarios(keptDir, "e2e-tag")
+	require.NoError(t, err)
+	require.Equal(t, []KeptScenario{kept}, loaded)
+
+	require.NoError(t, cleanup(log, keptDir, ""))
+
+	content, err := ioutil.ReadFile(filepath.Join(specParentDir, "after.txt"))
+	require.NoError(t, err)
+	require.Equal(t, "e2e-tag\n", string(content))
+
+	_, err = os.Stat(filepath.Join(keptDir, "e2e-tag"+keptScenarioFileExt))
+	require.True(t, os.IsNotExist(err))
+
+	require.ErrorIs(t, cleanup(log, keptDir, "e2e-tag"), ErrNoKeptScenario)
+}
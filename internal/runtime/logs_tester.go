@@ -0,0 +1,111 @@
+package runtime
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/newrelic/newrelic-client-go/pkg/nrdb"
+	"github.com/newrelic/newrelic-integration-e2e-action/internal/newrelic"
+	"github.com/newrelic/newrelic-integration-e2e-action/internal/spec"
+	"github.com/sirupsen/logrus"
+)
+
+const logMessageAttribute = "message"
+
+type LogsTester struct {
+	nrClient newrelic.Client
+	logger   *logrus.Logger
+}
+
+func NewLogsTester(nrClient newrelic.Client, logger *logrus.Logger) LogsTester {
+	return LogsTester{
+		nrClient: nrClient,
+		logger:   logger,
+	}
+}
+
+func (lt LogsTester) Test(tests spec.Tests, customTagKey, customTagValue string, timeRange newrelic.TimeRange) []error {
+	if len(tests.Logs) == 0 {
+		return nil
+	}
+
+	logs, err := lt.nrClient.FindLogs(customTagKey, customTagValue, timeRange)
+	if err != nil {
+		return []error{fmt.Errorf("finding logs: %w", err)}
+	}
+	lt.logger.Debugf("found %d logs", len(logs))
+
+	var errors []error
+	for _, tl := range tests.Logs {
+		// By default if not notified, we expect at least one matching log
+		if tl.MinCount == 0 {
+			tl.MinCount = 1
+		}
+
+		// The regex has already been validated when parsing the spec file.
+		var messageRegex *regexp.Regexp
+		if tl.MessageRegex != "" {
+			messageRegex = regexp.MustCompile(tl.MessageRegex)
+		}
+
+		matches := 0
+		for _, log := range logs {
+			if lt.matchesLog(log, tl, messageRegex) {
+				matches++
+			}
+		}
+
+		if matches < tl.MinCount {
+			errors = append(errors, fmt.Errorf("finding logs matching %s: got %d, expected at least %d", describeLogsTest(tl), matches, tl.MinCount))
+		}
+	}
+	return errors
+}
+
+func (lt LogsTester) matchesLog(log nrdb.NRDBResult, tl spec.TestLogs, messageRegex *regexp.Regexp) bool {
+	if tl.Message != "" || messageRegex != nil {
+		// Logs without message never match the message patterns, even the ones matching any text.
+		rawMessage, ok := log[logMessageAttribute]
+		if !ok || rawMessage == nil {
+			return false
+		}
+		if !matchesMessage(fmt.Sprintf("%v", rawMessage), tl, messageRegex) {
+			return false
+		}
+	}
+
+	for key, expected := range tl.Attributes {
+		actual, ok := log[key]
+		if !ok || fmt.Sprintf("%v", actual) != expected {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesMessage(message string, tl spec.TestLogs, messageRegex *regexp.Regexp) bool {
+	if tl.Message != "" && !strings.Contains(message, tl.Message) {
+		return false
+	}
+
+	return messageRegex == nil || messageRegex.MatchString(message)
+}
+
+// describeLogsTest returns the patterns of the logs test, used to identify it in failures.
+func describeLogsTest(tl spec.TestLogs) string {
+	var patterns []string
+	if tl.Message != "" {
+		patterns = append(patterns, fmt.Sprintf("message containing %q", tl.Message))
+	}
+	if tl.MessageRegex != "" {
+		patterns = append(patterns, fmt.Sprintf("message_regex %q", tl.MessageRegex))
+	}
+	if len(tl.Attributes) > 0 {
+		patterns = append(patterns, fmt.Sprintf("attributes %v", tl.Attributes))
+	}
+	if len(patterns) == 0 {
+		return "any log"
+	}
+	return strings.Join(patterns, " and ")
+}
@@ -0,0 +1,91 @@
+package runtime
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/newrelic/newrelic-integration-e2e-action/internal/newrelic"
+	"github.com/newrelic/newrelic-integration-e2e-action/internal/spec"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogsTester_Test(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+	logsTester := NewLogsTester(clientMock{}, log)
+
+	tests := []struct {
+		name                   string
+		logs                   []spec.TestLogs
+		numberOfErrorsExpected int
+	}{
+		{
+			name:                   "when any log is expected it should not return errors",
+			logs:                   []spec.TestLogs{{}},
+			numberOfErrorsExpected: 0,
+		},
+		{
+			name: "when the message substring and attributes match it should not return errors",
+			logs: []spec.TestLogs{
+				{Message: "starting integration", Attributes: map[string]string{"logtype": "integration", "hostname": "e2e-host"}},
+				{Attributes: map[string]string{"status": "200"}},
+			},
+			numberOfErrorsExpected: 0,
+		},
+		{
+			name: "when the message regex matches enough logs it should not return errors",
+			logs: []spec.TestLogs{
+				{MessageRegex: "integration", MinCount: 2},
+			},
+			numberOfErrorsExpected: 0,
+		},
+		{
+			name: "when the patterns do not match enough logs it should return one error per test",
+			logs: []spec.TestLogs{
+				{Message: "panic"},
+				{MessageRegex: "^starting", Attributes: map[string]string{"logtype": "access"}},
+				{Attributes: map[string]string{"logtype": "integration"}, MinCount: 3},
+			},
+			numberOfErrorsExpected: 3,
+		},
+		{
+			name: "when a log has no message it should not match the message patterns",
+			logs: []spec.TestLogs{
+				{MessageRegex: ".*", MinCount: 4},
+				{MessageRegex: "nil"},
+				{Message: "<nil>"},
+			},
+			numberOfErrorsExpected: 3,
+		},
+		{
+			name: "when a log has no message it should still match the attributes",
+			logs: []spec.TestLogs{
+				{Attributes: map[string]string{"status": "404"}},
+			},
+			numberOfErrorsExpected: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errors := logsTester.Test(spec.Tests{Logs: tt.logs}, "testKey", "e2e-tag", newrelic.TimeRange{})
+			require.Equal(t, tt.numberOfErrorsExpected, len(errors))
+		})
+	}
+}
+
+func TestLogsTester_Test_errorMessage(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+	logsTester := NewLogsTester(clientMock{}, log)
+
+	errors := logsTester.Test(spec.Tests{Logs: []spec.TestLogs{{MessageRegex: "panic: .*"}}}, "testKey", "e2e-tag", newrelic.TimeRange{})
+	require.Equal(t, 1, len(errors))
+	assert.Contains(t, errors[0].Error(), `message_regex "panic: .*"`)
+
+	errors = logsTester.Test(spec.Tests{Logs: []spec.TestLogs{{}}}, "testKey", errFindLogs, newrelic.TimeRange{})
+	require.Equal(t, 1, len(errors))
+	assert.ErrorIs(t, errors[0], ErrorTest)
+}
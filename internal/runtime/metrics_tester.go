@@ -3,18 +3,32 @@ package runtime
 import (
 	"fmt"
 	"io/ioutil"
+	"math"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/newrelic/newrelic-integration-e2e-action/internal/newrelic"
 	"github.com/newrelic/newrelic-integration-e2e-action/internal/spec"
 	"github.com/sirupsen/logrus"
 )
 
+const (
+	countType           = "count"
+	cumulativeCountType = "cumulativeCount"
+
+	// NRQL limits the number of buckets of a TIMESERIES query to 366.
+	cadenceMaxBuckets = 360
+)
+
 type MetricsTester struct {
 	nrClient      newrelic.Client
 	logger        *logrus.Logger
 	specParentDir string
+	// staleWarned holds the stale exceptions already logged, so they are not logged in every retry.
+	staleWarned map[string]bool
 }
 
 func NewMetricsTester(nrClient newrelic.Client, logger *logrus.Logger, specParentDir string) MetricsTester {
@@ -22,51 +36,189 @@ func NewMetricsTester(nrClient newrelic.Client, logger *logrus.Logger, specParen
 		nrClient:      nrClient,
 		logger:        logger,
 		specParentDir: specParentDir,
+		staleWarned:   map[string]bool{},
 	}
 }
 
-func (mt MetricsTester) Test(tests spec.Tests, customTagKey, customTagValue string) []error {
-	var errors []error
+// Lint logs the exceptions of the tests not matching the metrics source file, and the expired ones unless
+// they fail the test in strict mode. It is called once before the tests are retried.
+func (mt MetricsTester) Lint(tests spec.Tests) {
 	for _, tm := range tests.Metrics {
-		content, err := ioutil.ReadFile(filepath.Join(mt.specParentDir, tm.Source))
+		metrics, tm, err := mt.loadTest(tm)
 		if err != nil {
-			errors = append(errors, fmt.Errorf("reading metrics source file: %w", err))
+			// The error is returned by Test.
 			continue
 		}
-		mt.logger.Debug("parsing the content of the metrics source file")
-		metrics, err := spec.ParseMetricsFile(content)
+
+		mt.warnUnusedExceptions(metrics.Entities, tm)
+		if !tm.StrictExceptions {
+			for _, expired := range expiredExceptions(tm, time.Now()) {
+				mt.logger.Warn(expired)
+			}
+		}
+	}
+}
+
+func (mt MetricsTester) Test(tests spec.Tests, customTagKey, customTagValue string, timeRange newrelic.TimeRange) []error {
+	var errors []error
+	for _, tm := range tests.Metrics {
+		metrics, tm, err := mt.loadTest(tm)
 		if err != nil {
-			errors = append(errors, fmt.Errorf("unmarshaling metrics source file: %w", err))
+			errors = append(errors, err)
+			continue
+		}
+
+		// Every declared metric is taken into account to look for unexpected metrics.
+		declaredEntities := metrics.Entities
+		if tm.ServiceVersion != "" {
+			metrics.Entities, err = mt.filterByVersion(metrics.Entities, tm.ServiceVersion)
+			if err != nil {
+				errors = append(errors, fmt.Errorf("filtering metrics by service version: %w", err))
+				continue
+			}
+		}
+
+		if len(tm.EventTypes) > 0 {
+			for _, eventType := range tm.EventTypes {
+				queriedAttributes, err := mt.nrClient.FindEntityMetrics(eventType, customTagKey, customTagValue, timeRange)
+				if err != nil {
+					errors = append(errors, fmt.Errorf("finding keyset of %s: %w", eventType, err))
+					continue
+				}
+
+				errors = append(errors, mt.checkAttributes(metrics.Entities, eventType, tm, queriedAttributes)...)
+			}
 			continue
 		}
 
-		queriedMetrics, err := mt.nrClient.FindEntityMetrics(dmTableName, customTagKey, customTagValue)
+		queriedMetrics, err := mt.nrClient.FindEntityMetrics(spec.MetricDataType, customTagKey, customTagValue, timeRange)
 		if err != nil {
 			errors = append(errors, fmt.Errorf("finding keyset: %w", err))
 			continue
 		}
 
 		errors = append(errors, mt.checkMetrics(metrics.Entities, tm, queriedMetrics)...)
+
+		if tm.CheckDimensions {
+			errors = append(errors, mt.checkDimensions(metrics.Entities, tm, queriedMetrics, customTagKey, customTagValue, timeRange)...)
+		}
+
+		if tm.CheckTypes {
+			errors = append(errors, mt.checkTypes(metrics.Entities, tm, queriedMetrics, customTagKey, customTagValue, timeRange)...)
+		}
+
+		if tm.Cadence != nil {
+			errors = append(errors, mt.checkCadence(metrics.Entities, tm, queriedMetrics, customTagKey, customTagValue, timeRange)...)
+		}
+
+		errors = append(errors, mt.checkValues(metrics.Entities, tm, queriedMetrics, customTagKey, customTagValue, timeRange)...)
+
+		if tm.UnexpectedMetrics != nil {
+			reportedMetrics, err := mt.nrClient.FindMetricNames(customTagKey, customTagValue, timeRange)
+			if err != nil {
+				errors = append(errors, fmt.Errorf("finding metric names: %w", err))
+				continue
+			}
+
+			// The entity types are only needed to skip the metrics reported by excepted entities.
+			var metricEntityTypes map[string][]string
+			if len(tm.ExceptEntities) > 0 {
+				metricEntityTypes, err = mt.nrClient.FindMetricEntityTypes(customTagKey, customTagValue, timeRange)
+				if err != nil {
+					errors = append(errors, fmt.Errorf("finding entity types of the metrics: %w", err))
+					continue
+				}
+			}
+
+			errors = append(errors, mt.checkUnexpectedMetrics(declaredEntities, tm, reportedMetrics, metricEntityTypes)...)
+		}
 	}
 	return errors
 }
 
+// filterByVersion returns the entities with only the metrics reported by the given service version.
+func (mt MetricsTester) filterByVersion(entities []spec.Entity, serviceVersion string) ([]spec.Entity, error) {
+	filtered := make([]spec.Entity, 0, len(entities))
+	for _, entity := range entities {
+		var metrics []spec.Metric
+		for _, metric := range entity.Metrics {
+			supported, err := metric.SupportsVersion(serviceVersion)
+			if err != nil {
+				return nil, err
+			}
+			if !supported {
+				mt.logger.Debugf("skipping metric %s not reported by version %s", metric.Name, serviceVersion)
+				continue
+			}
+			metrics = append(metrics, metric)
+		}
+		filtered = append(filtered, spec.Entity{EntityType: entity.EntityType, Metrics: metrics})
+	}
+	return filtered, nil
+}
+
 func (mt MetricsTester) checkMetrics(entities []spec.Entity, tm spec.TestMetrics, queriedMetrics []string) []error {
 	var errors []error
+	for _, entity := range entities {
+		if mt.isEntityException(entity.EntityType, tm.ExceptEntities) {
+			continue
+		}
 
-	if tm.ExceptionsSource != "" {
-		exceptMetricsPath := filepath.Join(mt.specParentDir, tm.ExceptionsSource)
-		mt.logger.Debugf("parsing the content of the except metrics source file: %s", exceptMetricsPath)
+		for _, metric := range entity.Metrics {
+			if mt.isMetricException(metric.Name, tm.ExceptMetrics) {
+				continue
+			}
 
-		exceptions, err := parseExceptions(exceptMetricsPath)
-		if err != nil {
-			errors = append(errors, fmt.Errorf("reading except metrics source file %s: %w", exceptMetricsPath, err))
-			return errors
+			if mt.containsMetric(metric.Name, queriedMetrics) {
+				continue
+			}
+
+			errors = append(errors, fmt.Errorf("finding Metric: %v", metric.Name))
+		}
+	}
+	return append(errors, mt.checkExceptions(entities, tm, queriedMetrics)...)
+}
+
+// checkDimensions checks that every reported metric carries the dimensions declared in the spec file.
+// Metrics not reported are skipped since they are already reported by checkMetrics.
+func (mt MetricsTester) checkDimensions(entities []spec.Entity, tm spec.TestMetrics, queriedMetrics []string, customTagKey, customTagValue string, timeRange newrelic.TimeRange) []error {
+	var errors []error
+
+	for _, entity := range entities {
+		if mt.isEntityException(entity.EntityType, tm.ExceptEntities) {
+			continue
 		}
 
-		tm.ExceptMetrics = append(tm.ExceptMetrics, exceptions.ExceptMetrics...)
-		tm.ExceptEntities = append(tm.ExceptEntities, exceptions.ExceptEntities...)
+		for _, metric := range entity.Metrics {
+			if len(metric.Dimensions) == 0 || mt.isMetricException(metric.Name, tm.ExceptMetrics) || !mt.containsMetric(metric.Name, queriedMetrics) {
+				continue
+			}
+
+			queriedDimensions, err := mt.nrClient.FindMetricDimensions(metric.Name, customTagKey, customTagValue, timeRange)
+			if err != nil {
+				errors = append(errors, fmt.Errorf("finding dimensions of metric %s: %w", metric.Name, err))
+				continue
+			}
+
+			var missingDimensions []string
+			for _, dimension := range metric.DimensionNames() {
+				if mt.isMetricException(dimension, tm.ExceptDimensions) || mt.containsMetric(dimension, queriedDimensions) {
+					continue
+				}
+				missingDimensions = append(missingDimensions, dimension)
+			}
+
+			if len(missingDimensions) > 0 {
+				errors = append(errors, fmt.Errorf("finding dimensions of metric %s: %s", metric.Name, strings.Join(missingDimensions, ", ")))
+			}
+		}
 	}
+	return errors
+}
+
+// checkTypes checks that the type stored in NROne for every reported metric is the one declared in the spec file.
+func (mt MetricsTester) checkTypes(entities []spec.Entity, tm spec.TestMetrics, queriedMetrics []string, customTagKey, customTagValue string, timeRange newrelic.TimeRange) []error {
+	var errors []error
 
 	for _, entity := range entities {
 		if mt.isEntityException(entity.EntityType, tm.ExceptEntities) {
@@ -74,38 +226,491 @@ func (mt MetricsTester) checkMetrics(entities []spec.Entity, tm spec.TestMetrics
 		}
 
 		for _, metric := range entity.Metrics {
-			if mt.isMetricException(metric.Name, tm.ExceptMetrics) {
+			if mt.isMetricException(metric.Name, tm.ExceptMetrics) || !mt.containsMetric(metric.Name, queriedMetrics) {
 				continue
 			}
 
-			if mt.containsMetric(metric.Name, queriedMetrics) {
+			if metric.Type != "" {
+				errors = append(errors, mt.checkType(metric, customTagKey, customTagValue, timeRange)...)
+			}
+			if metric.Unit != "" {
+				errors = append(errors, mt.checkUnit(metric, customTagKey, customTagValue, timeRange)...)
+			}
+		}
+	}
+	return errors
+}
+
+func (mt MetricsTester) checkType(metric spec.Metric, customTagKey, customTagValue string, timeRange newrelic.TimeRange) []error {
+	queriedTypes, err := mt.nrClient.FindMetricTypes(metric.Name, customTagKey, customTagValue, timeRange)
+	if err != nil {
+		return []error{fmt.Errorf("finding type of metric %s: %w", metric.Name, err)}
+	}
+
+	for _, queriedType := range queriedTypes {
+		if !isSameMetricType(metric.Type, queriedType) {
+			return []error{fmt.Errorf("metric %s type is not matching: %s!=%s", metric.Name, queriedType, metric.Type)}
+		}
+	}
+	return nil
+}
+
+// checkUnit compares the unit stored with the metric with the declared one. Most integrations don't send the
+// unit with the data points, so metrics stored without unit are not checked.
+func (mt MetricsTester) checkUnit(metric spec.Metric, customTagKey, customTagValue string, timeRange newrelic.TimeRange) []error {
+	queriedUnits, err := mt.nrClient.FindMetricUnits(metric.Name, customTagKey, customTagValue, timeRange)
+	if err != nil {
+		return []error{fmt.Errorf("finding unit of metric %s: %w", metric.Name, err)}
+	}
+	if len(queriedUnits) == 0 {
+		mt.logger.Debugf("metric %s is stored without unit, not checking it", metric.Name)
+		return nil
+	}
+
+	for _, queriedUnit := range queriedUnits {
+		if !strings.EqualFold(metric.Unit, queriedUnit) {
+			return []error{fmt.Errorf("metric %s unit is not matching: %s!=%s", metric.Name, queriedUnit, metric.Unit)}
+		}
+	}
+	return nil
+}
+
+// isSameMetricType compares the declared and stored types, taking into account that counts can be
+// stored as cumulative counts.
+func isSameMetricType(declared, stored string) bool {
+	if strings.EqualFold(stored, cumulativeCountType) {
+		stored = countType
+	}
+	return strings.EqualFold(declared, stored)
+}
+
+// checkCadence checks that every reported metric has data points at the interval of its defaultResolution.
+// The data points of each entity are counted in buckets of one resolution, and compared with the number of
+// intervals of the resolution, widened by the tolerance, between its first and last data points.
+func (mt MetricsTester) checkCadence(entities []spec.Entity, tm spec.TestMetrics, queriedMetrics []string, customTagKey, customTagValue string, timeRange newrelic.TimeRange) []error {
+	var errors []error
+
+	for _, entity := range entities {
+		if mt.isEntityException(entity.EntityType, tm.ExceptEntities) {
+			continue
+		}
+
+		for _, metric := range entity.Metrics {
+			if metric.DefaultResolution <= 0 || mt.isMetricException(metric.Name, tm.ExceptMetrics) || !mt.containsMetric(metric.Name, queriedMetrics) {
 				continue
 			}
 
-			errors = append(errors, fmt.Errorf("finding Metric: %v", metric.Name))
+			bucketSeconds := metric.DefaultResolution
+			pointsByEntity, err := mt.nrClient.FindMetricTimeseries(metric.Name, bucketSeconds, customTagKey, customTagValue, cadenceTimeRange(timeRange, bucketSeconds))
+			if err != nil {
+				errors = append(errors, fmt.Errorf("finding timeseries of metric %s: %w", metric.Name, err))
+				continue
+			}
+
+			for _, guid := range sortedKeys(pointsByEntity) {
+				if missed := countMissedCycles(pointsByEntity[guid], tm.Cadence.Tolerance); missed > tm.Cadence.MaxGaps {
+					errors = append(errors, fmt.Errorf(
+						"metric %s of entity %s is not reported every %ds: %d reporting cycles missed, expected at most %d",
+						metric.Name, guid, metric.DefaultResolution, missed, tm.Cadence.MaxGaps,
+					))
+				}
+			}
 		}
 	}
 	return errors
 }
 
-func (mt MetricsTester) isEntityException(entity string, entitiesList []string) bool {
-	for _, entityType := range entitiesList {
-		if entityType == entity {
+// cadenceTimeRange returns the time range of the tests limited to the last buckets allowed by a TIMESERIES
+// query.
+func cadenceTimeRange(timeRange newrelic.TimeRange, bucketSeconds int) newrelic.TimeRange {
+	until := timeRange.Until
+	if until.IsZero() {
+		until = time.Now()
+	}
+
+	earliest := until.Add(-time.Duration(bucketSeconds*cadenceMaxBuckets) * time.Second)
+	if timeRange.Since.Before(earliest) {
+		return newrelic.TimeRange{Since: earliest, Until: until}
+	}
+	return newrelic.TimeRange{Since: timeRange.Since, Until: until}
+}
+
+func sortedKeys(points map[string][]float64) []string {
+	keys := make([]string, 0, len(points))
+	for key := range points {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// countMissedCycles returns the number of reporting cycles missed by an entity, given its data points in
+// buckets of one resolution. Since the data points can be anywhere in their buckets, the time between the
+// first and the last data point is at least the buckets between them, which holds that number of intervals
+// of the resolution widened by the tolerance, plus one data point.
+func countMissedCycles(points []float64, tolerance float64) int {
+	first, last := -1, -1
+	reported := 0.0
+	for i, p := range points {
+		if p > 0 {
+			if first < 0 {
+				first = i
+			}
+			last = i
+			reported += p
+		}
+	}
+	if first < 0 || last-first < 2 {
+		return 0
+	}
+
+	expected := int(math.Floor(float64(last-first-1)/(1+tolerance))) + 1
+	if missed := expected - int(math.Round(reported)); missed > 0 {
+		return missed
+	}
+	return 0
+}
+
+// checkValues evaluates, for every entity reporting a metric, the value rules of the metric from the test,
+// or from the spec file when the test does not define them.
+func (mt MetricsTester) checkValues(entities []spec.Entity, tm spec.TestMetrics, queriedMetrics []string, customTagKey, customTagValue string, timeRange newrelic.TimeRange) []error {
+	var errors []error
+
+	for _, entity := range entities {
+		if mt.isEntityException(entity.EntityType, tm.ExceptEntities) {
+			continue
+		}
+
+		for _, metric := range entity.Metrics {
+			rules := metric.ValueRules
+			if testRules, ok := tm.ValueRules[metric.Name]; ok {
+				rules = &testRules
+			}
+
+			if rules == nil || mt.isMetricException(metric.Name, tm.ExceptMetrics) || !mt.containsMetric(metric.Name, queriedMetrics) {
+				continue
+			}
+
+			values, err := mt.nrClient.FindMetricValues(metric.Name, customTagKey, customTagValue, timeRange)
+			if err != nil {
+				errors = append(errors, fmt.Errorf("finding values of metric %s: %w", metric.Name, err))
+				continue
+			}
+
+			for _, v := range values {
+				for _, violation := range valueRulesViolations(*rules, v) {
+					errors = append(errors, fmt.Errorf("metric %s of entity %s %s", metric.Name, v.Entity, violation))
+				}
+			}
+		}
+	}
+	return errors
+}
+
+// valueRulesViolations returns a description of each rule not satisfied by the metric values.
+func valueRulesViolations(rules spec.ValueRules, values newrelic.MetricValues) []string {
+	if values.Min == nil || values.Max == nil {
+		return []string{"has no numeric values"}
+	}
+	minValue, maxValue := *values.Min, *values.Max
+
+	var violations []string
+	if rules.NonNegative && minValue < 0 {
+		violations = append(violations, fmt.Sprintf("is negative: min %f", minValue))
+	}
+	if rules.NonZero && minValue == 0 && maxValue == 0 {
+		violations = append(violations, "is always zero")
+	}
+	if rules.Min != nil && minValue < *rules.Min {
+		violations = append(violations, fmt.Sprintf("is below %f: min %f", *rules.Min, minValue))
+	}
+	if rules.Max != nil && maxValue > *rules.Max {
+		violations = append(violations, fmt.Sprintf("is above %f: max %f", *rules.Max, maxValue))
+	}
+	if rules.MustChange && minValue == maxValue {
+		violations = append(violations, fmt.Sprintf("did not change: always %f", minValue))
+	}
+	return violations
+}
+
+// checkUnexpectedMetrics reports the metrics sent during the scenario that are not declared in the spec file,
+// either as warnings or as errors depending on the configured mode. Metrics only reported by the excepted
+// entity types, found in metricEntityTypes, are skipped.
+func (mt MetricsTester) checkUnexpectedMetrics(entities []spec.Entity, tm spec.TestMetrics, reportedMetrics []string, metricEntityTypes map[string][]string) []error {
+	declaredMetrics := map[string]bool{}
+	for _, entity := range entities {
+		for _, metric := range entity.Metrics {
+			declaredMetrics[metric.Name] = true
+		}
+	}
+
+	var unexpectedMetrics []string
+	for _, reportedMetric := range reportedMetrics {
+		if declaredMetrics[reportedMetric] || matchesAnyPattern(reportedMetric, tm.UnexpectedMetrics.Allow) {
+			continue
+		}
+		if mt.reportedByExceptedEntities(metricEntityTypes[reportedMetric], tm.ExceptEntities) {
+			continue
+		}
+		unexpectedMetrics = append(unexpectedMetrics, reportedMetric)
+	}
+
+	if len(unexpectedMetrics) == 0 {
+		return nil
+	}
+
+	if tm.UnexpectedMetrics.Mode == spec.UnexpectedMetricsWarn {
+		mt.logger.Warnf("found metrics not declared in %s: %s", tm.Source, strings.Join(unexpectedMetrics, ", "))
+		return nil
+	}
+
+	var errors []error
+	for _, unexpectedMetric := range unexpectedMetrics {
+		errors = append(errors, fmt.Errorf("found Metric not declared in %s: %v", tm.Source, unexpectedMetric))
+	}
+	return errors
+}
+
+// checkAttributes checks that the legacy attributes of the metrics and dimensions mapped to the
+// eventType in the spec file are present in the queried keyset of the sample.
+func (mt MetricsTester) checkAttributes(entities []spec.Entity, eventType string, tm spec.TestMetrics, queriedAttributes []string) []error {
+	errors := mt.checkExceptions(entities, tm, nil)
+
+	checkedDimensions := map[string]bool{}
+	for _, entity := range entities {
+		if mt.isEntityException(entity.EntityType, tm.ExceptEntities) {
+			continue
+		}
+
+		for _, metric := range entity.Metrics {
+			if mt.isMetricException(metric.Name, tm.ExceptMetrics) {
+				continue
+			}
+
+			if metric.MigrationInformation.IsLegacyEventType(eventType) {
+				if err := mt.checkLegacyNames(metric.MigrationInformation.LegacyNames, eventType, tm, queriedAttributes); err != nil {
+					errors = append(errors, err)
+				}
+			}
+
+			for _, dimension := range metric.Dimensions {
+				if !dimension.MigrationInformation.IsLegacyEventType(eventType) || checkedDimensions[dimension.Name] {
+					continue
+				}
+				checkedDimensions[dimension.Name] = true
+
+				if err := mt.checkLegacyNames(dimension.MigrationInformation.LegacyNames, eventType, tm, queriedAttributes); err != nil {
+					errors = append(errors, err)
+				}
+			}
+		}
+	}
+	return errors
+}
+
+// checkLegacyNames returns an error unless one of the legacy names, which are alternative names for
+// the same attribute, is present in the queried attributes or excepted.
+func (mt MetricsTester) checkLegacyNames(legacyNames []string, eventType string, tm spec.TestMetrics, queriedAttributes []string) error {
+	if len(legacyNames) == 0 {
+		return nil
+	}
+
+	for _, legacyName := range legacyNames {
+		if mt.isMetricException(legacyName, tm.ExceptMetrics) || mt.containsMetric(legacyName, queriedAttributes) {
+			return nil
+		}
+	}
+	return fmt.Errorf("finding %s attribute: %v", eventType, strings.Join(legacyNames, "|"))
+}
+
+// loadTest returns the metrics source file of the test, and the test with the exceptions of its exceptions
+// source file appended.
+func (mt MetricsTester) loadTest(tm spec.TestMetrics) (*spec.Metrics, spec.TestMetrics, error) {
+	content, err := ioutil.ReadFile(filepath.Join(mt.specParentDir, tm.Source))
+	if err != nil {
+		return nil, tm, fmt.Errorf("reading metrics source file: %w", err)
+	}
+	mt.logger.Debug("parsing the content of the metrics source file")
+	metrics, err := spec.ParseMetricsFile(content)
+	if err != nil {
+		return nil, tm, fmt.Errorf("unmarshaling metrics source file: %w", err)
+	}
+
+	tm, err = mt.mergeExceptionsSource(tm)
+	if err != nil {
+		return nil, tm, err
+	}
+	return metrics, tm, nil
+}
+
+// mergeExceptionsSource returns the test with the exceptions from its exceptions source file appended.
+func (mt MetricsTester) mergeExceptionsSource(tm spec.TestMetrics) (spec.TestMetrics, error) {
+	if tm.ExceptionsSource == "" {
+		return tm, nil
+	}
+
+	exceptMetricsPath := filepath.Join(mt.specParentDir, tm.ExceptionsSource)
+	mt.logger.Debugf("parsing the content of the except metrics source file: %s", exceptMetricsPath)
+
+	exceptions, err := parseExceptions(exceptMetricsPath)
+	if err != nil {
+		return tm, fmt.Errorf("reading except metrics source file %s: %w", exceptMetricsPath, err)
+	}
+
+	tm.ExceptMetrics = append(tm.ExceptMetrics, exceptions.ExceptMetrics...)
+	tm.ExceptEntities = append(tm.ExceptEntities, exceptions.ExceptEntities...)
+	tm.ExceptDimensions = append(tm.ExceptDimensions, exceptions.ExceptDimensions...)
+	return tm, nil
+}
+
+func (mt MetricsTester) isEntityException(entity string, exceptions []spec.Exception) bool {
+	for _, exception := range exceptions {
+		if exception.Matches(entity) {
 			return true
 		}
 	}
 	return false
 }
 
-func (mt MetricsTester) isMetricException(metric string, exceptionMetricsList []string) bool {
-	for _, exceptMetric := range exceptionMetricsList {
-		if exceptMetric == metric {
+// reportedByExceptedEntities returns true if the metric is only reported by entity types excepted by the test.
+func (mt MetricsTester) reportedByExceptedEntities(entityTypes []string, exceptions []spec.Exception) bool {
+	if len(entityTypes) == 0 {
+		return false
+	}
+	for _, entityType := range entityTypes {
+		if !mt.isEntityException(entityType, exceptions) {
+			return false
+		}
+	}
+	return true
+}
+
+func (mt MetricsTester) isMetricException(metric string, exceptions []spec.Exception) bool {
+	for _, exception := range exceptions {
+		if exception.Matches(metric) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAnyPattern(value string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if spec.MatchesPattern(pattern, value) {
 			return true
 		}
 	}
 	return false
 }
 
+// warnUnusedExceptions logs the exception patterns not matching any entity, metric or dimension of the
+// spec file, so dead exceptions can be cleaned up.
+func (mt MetricsTester) warnUnusedExceptions(entities []spec.Entity, tm spec.TestMetrics) {
+	var entityTypes, metricNames, dimensionNames []string
+	for _, entity := range entities {
+		entityTypes = append(entityTypes, entity.EntityType)
+		for _, metric := range entity.Metrics {
+			metricNames = append(metricNames, metric.Name)
+			if metric.MigrationInformation != nil {
+				metricNames = append(metricNames, metric.MigrationInformation.LegacyNames...)
+			}
+			dimensionNames = append(dimensionNames, metric.DimensionNames()...)
+		}
+	}
+
+	for _, unused := range unusedPatterns(spec.ExceptionNames(tm.ExceptEntities), entityTypes) {
+		mt.logger.Warnf("except_entities pattern %q does not match any entity in %s", unused, tm.Source)
+	}
+	for _, unused := range unusedPatterns(spec.ExceptionNames(tm.ExceptMetrics), metricNames) {
+		mt.logger.Warnf("except_metrics pattern %q does not match any metric in %s", unused, tm.Source)
+	}
+	for _, unused := range unusedPatterns(spec.ExceptionNames(tm.ExceptDimensions), dimensionNames) {
+		mt.logger.Warnf("except_dimensions pattern %q does not match any dimension in %s", unused, tm.Source)
+	}
+}
+
+// checkExceptions reports expired exceptions and, when the queried metrics are given, metric exceptions
+// that are stale because every metric they except is reported. Those fail the test in strict mode.
+// Otherwise the expired exceptions are logged by Lint, and each stale exception is logged once.
+func (mt MetricsTester) checkExceptions(entities []spec.Entity, tm spec.TestMetrics, queriedMetrics []string) []error {
+	var stale []string
+	if queriedMetrics != nil {
+		stale = mt.staleExceptions(entities, tm, queriedMetrics)
+	}
+
+	if !tm.StrictExceptions {
+		for _, problem := range stale {
+			if !mt.staleWarned[problem] {
+				mt.staleWarned[problem] = true
+				mt.logger.Warn(problem)
+			}
+		}
+		return nil
+	}
+
+	var errors []error
+	for _, problem := range append(expiredExceptions(tm, time.Now()), stale...) {
+		errors = append(errors, fmt.Errorf("%s", problem))
+	}
+	return errors
+}
+
+func expiredExceptions(tm spec.TestMetrics, now time.Time) []string {
+	var expired []string
+	for _, exceptions := range [][]spec.Exception{tm.ExceptEntities, tm.ExceptMetrics, tm.ExceptDimensions} {
+		for _, exception := range exceptions {
+			if exception.Expired(now) {
+				expired = append(expired, fmt.Sprintf("exception %s expired on %s", exception, exception.Until))
+			}
+		}
+	}
+	return expired
+}
+
+func (mt MetricsTester) staleExceptions(entities []spec.Entity, tm spec.TestMetrics, queriedMetrics []string) []string {
+	var stale []string
+	for _, exception := range tm.ExceptMetrics {
+		excepted, missing := 0, 0
+		for _, entity := range entities {
+			if mt.isEntityException(entity.EntityType, tm.ExceptEntities) {
+				continue
+			}
+			for _, metric := range entity.Metrics {
+				if !exception.Matches(metric.Name) {
+					continue
+				}
+				excepted++
+				if !mt.containsMetric(metric.Name, queriedMetrics) {
+					missing++
+				}
+			}
+		}
+
+		if excepted > 0 && missing == 0 {
+			stale = append(stale, fmt.Sprintf("exception %s is stale, all the metrics it excepts are reported", exception))
+		}
+	}
+	return stale
+}
+
+// unusedPatterns returns the patterns not matching any of the values.
+func unusedPatterns(patterns []string, values []string) []string {
+	var unused []string
+	for _, pattern := range patterns {
+		matched := false
+		for _, value := range values {
+			if spec.MatchesPattern(pattern, value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			unused = append(unused, pattern)
+		}
+	}
+	return unused
+}
+
 func (mt MetricsTester) containsMetric(metric string, queriedMetricsList []string) bool {
 	for _, queriedMetric := range queriedMetricsList {
 		if queriedMetric == metric {
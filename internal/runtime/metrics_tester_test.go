@@ -3,9 +3,12 @@ package runtime
 import (
 	"io/ioutil"
 	"testing"
+	"time"
 
+	"github.com/newrelic/newrelic-integration-e2e-action/internal/newrelic"
 	"github.com/newrelic/newrelic-integration-e2e-action/internal/spec"
 	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -25,7 +28,7 @@ func TestMetricsTester_Test(t *testing.T) {
 		},
 	}}
 
-	errors := metricsTester.Test(inputTests, "", "")
+	errors := metricsTester.Test(inputTests, "", "", newrelic.TimeRange{})
 	assert.Equal(t, 0, len(errors))
 }
 
@@ -38,14 +41,14 @@ func TestMetricsTester_checkMetrics(t *testing.T) {
 		{
 			EntityType: "ENTITY-A",
 			Metrics: []spec.Metric{
-				{"metric-A"},
+				{Name: "metric-A"},
 			},
 		},
 		{
 			EntityType: "ENTITY-B",
 			Metrics: []spec.Metric{
-				{"metric-B1"},
-				{"metric-B2"},
+				{Name: "metric-B1"},
+				{Name: "metric-B2"},
 			},
 		},
 	}
@@ -61,8 +64,8 @@ func TestMetricsTester_checkMetrics(t *testing.T) {
 			testMetrics: spec.TestMetrics{
 				Source: "",
 				Exceptions: spec.Exceptions{
-					ExceptEntities: []string{},
-					ExceptMetrics:  []string{},
+					ExceptEntities: []spec.Exception{},
+					ExceptMetrics:  []spec.Exception{},
 				},
 			},
 			queriedMetrics:         []string{},
@@ -73,8 +76,8 @@ func TestMetricsTester_checkMetrics(t *testing.T) {
 			testMetrics: spec.TestMetrics{
 				Source: "",
 				Exceptions: spec.Exceptions{
-					ExceptEntities: []string{"ENTITY-A"},
-					ExceptMetrics:  []string{},
+					ExceptEntities: []spec.Exception{{Name: "ENTITY-A"}},
+					ExceptMetrics:  []spec.Exception{},
 				},
 			},
 			queriedMetrics:         []string{"metric-B1", "metric-B2"},
@@ -85,20 +88,32 @@ func TestMetricsTester_checkMetrics(t *testing.T) {
 			testMetrics: spec.TestMetrics{
 				Source: "",
 				Exceptions: spec.Exceptions{
-					ExceptEntities: []string{},
-					ExceptMetrics:  []string{"metric-A"},
+					ExceptEntities: []spec.Exception{},
+					ExceptMetrics:  []spec.Exception{{Name: "metric-A"}},
 				},
 			},
 			queriedMetrics:         []string{"metric-B1", "metric-B2"},
 			numberOfErrorsExpected: 0,
 		},
+		{
+			name: "when metrics and entities are excluded by glob and regex patterns it shouldn't return errors",
+			testMetrics: spec.TestMetrics{
+				Source: "",
+				Exceptions: spec.Exceptions{
+					ExceptEntities: []spec.Exception{{Name: "ENTITY-?"}},
+					ExceptMetrics:  []spec.Exception{{Name: "metric-B*"}, {Name: "regex:metric-(A|C)"}},
+				},
+			},
+			queriedMetrics:         []string{},
+			numberOfErrorsExpected: 0,
+		},
 		{
 			name: "when a metric is excluded from exceptions file source it shouldn't return errors",
 			testMetrics: spec.TestMetrics{
 				Source: "",
 				Exceptions: spec.Exceptions{
-					ExceptEntities: []string{},
-					ExceptMetrics:  []string{"metric-B1"},
+					ExceptEntities: []spec.Exception{},
+					ExceptMetrics:  []spec.Exception{{Name: "metric-B1"}},
 				},
 				ExceptionsSource: "testdata/exceptions_metric.yml",
 			},
@@ -110,7 +125,7 @@ func TestMetricsTester_checkMetrics(t *testing.T) {
 			testMetrics: spec.TestMetrics{
 				Source: "",
 				Exceptions: spec.Exceptions{
-					ExceptEntities: []string{"ENTITY-B"},
+					ExceptEntities: []spec.Exception{{Name: "ENTITY-B"}},
 				},
 				ExceptionsSource: "testdata/exceptions_entity.yml",
 			},
@@ -119,6 +134,517 @@ func TestMetricsTester_checkMetrics(t *testing.T) {
 		},
 	}
 
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testMetrics, err := metricsTester.mergeExceptionsSource(tt.testMetrics)
+			require.NoError(t, err)
+
+			errors := metricsTester.checkMetrics(entities, testMetrics, tt.queriedMetrics)
+			require.Equal(t, tt.numberOfErrorsExpected, len(errors))
+		})
+	}
+}
+
+func TestMetricsTester_checkAttributes(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+	metricsTester := NewMetricsTester(clientMock{}, log, "")
+
+	brokerSample := "KafkaBrokerSample"
+	topicSample := "KafkaTopicSample"
+	entities := []spec.Entity{
+		{
+			EntityType: "ENTITY-A",
+			Metrics: []spec.Metric{
+				{
+					Name: "metric-A1",
+					MigrationInformation: &spec.MigrationInformation{
+						LegacyEventType: brokerSample,
+						LegacyNames:     []string{"attribute-A1"},
+					},
+					Dimensions: []spec.Dimension{
+						{
+							Name: "dimension-A",
+							MigrationInformation: &spec.MigrationInformation{
+								LegacyEventTypes: []string{brokerSample},
+								LegacyNames:      []string{"legacy-dimension-A"},
+							},
+						},
+					},
+				},
+				{
+					Name: "metric-A2",
+					MigrationInformation: &spec.MigrationInformation{
+						LegacyEventType: brokerSample,
+						LegacyNames:     []string{"attribute-A2", "attribute-A2-old"},
+					},
+					Dimensions: []spec.Dimension{
+						{
+							Name: "dimension-A",
+							MigrationInformation: &spec.MigrationInformation{
+								LegacyEventTypes: []string{brokerSample},
+								LegacyNames:      []string{"legacy-dimension-A"},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			EntityType: "ENTITY-B",
+			Metrics: []spec.Metric{
+				{
+					Name: "metric-B",
+					MigrationInformation: &spec.MigrationInformation{
+						LegacyEventType: topicSample,
+						LegacyNames:     []string{"attribute-B"},
+					},
+				},
+				{Name: "metric-without-migration-information"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name                   string
+		eventType              string
+		testMetrics            spec.TestMetrics
+		queriedAttributes      []string
+		numberOfErrorsExpected int
+	}{
+		{
+			name:                   "when no attributes it should return one error for each missing attribute and dimension of the event type",
+			eventType:              brokerSample,
+			queriedAttributes:      []string{},
+			numberOfErrorsExpected: 3,
+		},
+		{
+			name:                   "when all attributes or one of their legacy names are present it shouldn't return errors",
+			eventType:              brokerSample,
+			queriedAttributes:      []string{"attribute-A1", "attribute-A2-old", "legacy-dimension-A"},
+			numberOfErrorsExpected: 0,
+		},
+		{
+			name:                   "when attributes from other event types are missing it shouldn't return errors",
+			eventType:              topicSample,
+			queriedAttributes:      []string{"attribute-B"},
+			numberOfErrorsExpected: 0,
+		},
+		{
+			name:      "when missing attributes are excepted by metric or attribute name it shouldn't return errors",
+			eventType: brokerSample,
+			testMetrics: spec.TestMetrics{
+				Exceptions: spec.Exceptions{
+					ExceptMetrics: []spec.Exception{{Name: "metric-A1"}, {Name: "attribute-A2"}},
+				},
+			},
+			queriedAttributes:      []string{"legacy-dimension-A"},
+			numberOfErrorsExpected: 0,
+		},
+		{
+			name:      "when the entity is excepted it shouldn't return errors",
+			eventType: brokerSample,
+			testMetrics: spec.TestMetrics{
+				Exceptions: spec.Exceptions{
+					ExceptEntities: []spec.Exception{{Name: "ENTITY-A"}},
+				},
+			},
+			queriedAttributes:      []string{},
+			numberOfErrorsExpected: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errors := metricsTester.checkAttributes(entities, tt.eventType, tt.testMetrics, tt.queriedAttributes)
+			require.Equal(t, tt.numberOfErrorsExpected, len(errors))
+		})
+	}
+}
+
+func TestMetricsTester_checkDimensions(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+	metricsTester := NewMetricsTester(clientMock{}, log, "")
+
+	entities := []spec.Entity{
+		{
+			EntityType: "ENTITY-A",
+			Metrics: []spec.Metric{
+				{Name: "metric-A1", Dimensions: []spec.Dimension{{Name: "proto"}, {Name: "type"}}},
+				{Name: "metric-A2", Dimensions: []spec.Dimension{{Name: "proto"}, {Name: "server"}, {Name: "zone"}}},
+				{Name: "metric-A3"},
+			},
+		},
+		{
+			EntityType: "ENTITY-B",
+			Metrics: []spec.Metric{
+				{Name: errFindMetricDimensions, Dimensions: []spec.Dimension{{Name: "proto"}}},
+			},
+		},
+	}
+
+	tests := []struct {
+		name                   string
+		testMetrics            spec.TestMetrics
+		queriedMetrics         []string
+		numberOfErrorsExpected int
+	}{
+		{
+			name:                   "when a metric misses dimensions it should return one error for the metric",
+			queriedMetrics:         []string{"metric-A1", "metric-A2", "metric-A3"},
+			numberOfErrorsExpected: 1,
+		},
+		{
+			name:                   "when the metrics are not reported it shouldn't check their dimensions",
+			queriedMetrics:         []string{"metric-A1"},
+			numberOfErrorsExpected: 0,
+		},
+		{
+			name:                   "when the dimensions query fails it should return an error",
+			queriedMetrics:         []string{errFindMetricDimensions},
+			numberOfErrorsExpected: 1,
+		},
+		{
+			name: "when the missing dimensions are excepted it shouldn't return errors",
+			testMetrics: spec.TestMetrics{
+				Exceptions: spec.Exceptions{
+					ExceptDimensions: []spec.Exception{{Name: "server"}, {Name: "zone"}},
+				},
+			},
+			queriedMetrics:         []string{"metric-A1", "metric-A2"},
+			numberOfErrorsExpected: 0,
+		},
+		{
+			name: "when the metric or entity is excepted it shouldn't return errors",
+			testMetrics: spec.TestMetrics{
+				Exceptions: spec.Exceptions{
+					ExceptMetrics:  []spec.Exception{{Name: "metric-A2"}},
+					ExceptEntities: []spec.Exception{{Name: "ENTITY-B"}},
+				},
+			},
+			queriedMetrics:         []string{"metric-A1", "metric-A2", errFindMetricDimensions},
+			numberOfErrorsExpected: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errors := metricsTester.checkDimensions(entities, tt.testMetrics, tt.queriedMetrics, "", "", newrelic.TimeRange{})
+			require.Equal(t, tt.numberOfErrorsExpected, len(errors))
+		})
+	}
+}
+
+func TestMetricsTester_checkTypes(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+	metricsTester := NewMetricsTester(clientMock{}, log, "")
+
+	tests := []struct {
+		name                   string
+		metrics                []spec.Metric
+		testMetrics            spec.TestMetrics
+		numberOfErrorsExpected int
+	}{
+		{
+			name:                   "when the stored type matches the declared one it shouldn't return errors",
+			metrics:                []spec.Metric{{Name: "metric-A", Type: "gauge"}, {Name: "metric-B", Type: "GAUGE"}},
+			numberOfErrorsExpected: 0,
+		},
+		{
+			name:                   "when a count is stored as cumulative count it shouldn't return errors",
+			metrics:                []spec.Metric{{Name: cumulativeCountMetric, Type: "count"}},
+			numberOfErrorsExpected: 0,
+		},
+		{
+			name:                   "when the metric has no declared type it shouldn't return errors",
+			metrics:                []spec.Metric{{Name: "metric-A"}},
+			numberOfErrorsExpected: 0,
+		},
+		{
+			name:                   "when the stored type doesn't match the declared one it should return one error per metric",
+			metrics:                []spec.Metric{{Name: "metric-A", Type: "count"}, {Name: mixedTypesMetric, Type: "gauge"}},
+			numberOfErrorsExpected: 2,
+		},
+		{
+			name:                   "when the types query fails it should return an error",
+			metrics:                []spec.Metric{{Name: errFindMetricTypes, Type: "gauge"}},
+			numberOfErrorsExpected: 1,
+		},
+		{
+			name:                   "when the stored unit matches the declared one it shouldn't return errors",
+			metrics:                []spec.Metric{{Name: secondsUnitMetric, Type: "gauge", Unit: "Seconds"}},
+			numberOfErrorsExpected: 0,
+		},
+		{
+			name:                   "when the metric is stored without unit it shouldn't return errors",
+			metrics:                []spec.Metric{{Name: "metric-A", Unit: "bytes"}},
+			numberOfErrorsExpected: 0,
+		},
+		{
+			name:                   "when the stored unit doesn't match the declared one it should return an error",
+			metrics:                []spec.Metric{{Name: secondsUnitMetric, Unit: "milliseconds"}},
+			numberOfErrorsExpected: 1,
+		},
+		{
+			name:                   "when the units query fails it should return an error",
+			metrics:                []spec.Metric{{Name: errFindMetricUnits, Unit: "seconds"}},
+			numberOfErrorsExpected: 1,
+		},
+		{
+			name:    "when the metric is excepted it shouldn't return errors",
+			metrics: []spec.Metric{{Name: "metric-A", Type: "summary", Unit: "seconds"}},
+			testMetrics: spec.TestMetrics{
+				Exceptions: spec.Exceptions{ExceptMetrics: []spec.Exception{{Name: "metric-A"}}},
+			},
+			numberOfErrorsExpected: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var queriedMetrics []string
+			for _, metric := range tt.metrics {
+				queriedMetrics = append(queriedMetrics, metric.Name)
+			}
+			entities := []spec.Entity{{EntityType: "ENTITY-A", Metrics: tt.metrics}}
+
+			errors := metricsTester.checkTypes(entities, tt.testMetrics, queriedMetrics, "", "", newrelic.TimeRange{})
+			require.Equal(t, tt.numberOfErrorsExpected, len(errors))
+		})
+	}
+}
+
+func TestMetricsTester_checkCadence(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+	metricsTester := NewMetricsTester(clientMock{}, log, "")
+
+	tests := []struct {
+		name                   string
+		metrics                []spec.Metric
+		cadence                spec.Cadence
+		numberOfErrorsExpected int
+	}{
+		{
+			name:                   "when the metric is reported every interval it shouldn't return errors",
+			metrics:                []spec.Metric{{Name: "metric-A", DefaultResolution: 15}},
+			numberOfErrorsExpected: 0,
+		},
+		{
+			name:                   "when an entity skips intervals of the metric it should return an error",
+			metrics:                []spec.Metric{{Name: gapsMetric, DefaultResolution: 15}},
+			numberOfErrorsExpected: 1,
+		},
+		{
+			name:                   "when the metric skips fewer intervals than allowed it shouldn't return errors",
+			metrics:                []spec.Metric{{Name: gapsMetric, DefaultResolution: 15}},
+			cadence:                spec.Cadence{MaxGaps: 3},
+			numberOfErrorsExpected: 0,
+		},
+		{
+			name:                   "when an entity skips every other interval it should return an error",
+			metrics:                []spec.Metric{{Name: everyOtherCycleMetric, DefaultResolution: 15}},
+			cadence:                spec.Cadence{MaxGaps: 1, Tolerance: 0.2},
+			numberOfErrorsExpected: 1,
+		},
+		{
+			name:                   "when the metric has no resolution it shouldn't return errors",
+			metrics:                []spec.Metric{{Name: gapsMetric}},
+			numberOfErrorsExpected: 0,
+		},
+		{
+			name:                   "when the timeseries query fails it should return an error",
+			metrics:                []spec.Metric{{Name: errFindMetricTimeseries, DefaultResolution: 15}},
+			numberOfErrorsExpected: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var queriedMetrics []string
+			for _, metric := range tt.metrics {
+				queriedMetrics = append(queriedMetrics, metric.Name)
+			}
+			entities := []spec.Entity{{EntityType: "ENTITY-A", Metrics: tt.metrics}}
+			testMetrics := spec.TestMetrics{Cadence: &tt.cadence}
+
+			errors := metricsTester.checkCadence(entities, testMetrics, queriedMetrics, "", "", newrelic.TimeRange{})
+			require.Equal(t, tt.numberOfErrorsExpected, len(errors))
+		})
+	}
+}
+
+func Test_cadenceTimeRange(t *testing.T) {
+	until := time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	scenarioStart := until.Add(-10 * time.Minute)
+	assert.Equal(t, newrelic.TimeRange{Since: scenarioStart, Until: until}, cadenceTimeRange(newrelic.TimeRange{Since: scenarioStart, Until: until}, 30))
+
+	// 360 buckets of 30s are 3 hours.
+	longScenarioStart := until.Add(-5 * time.Hour)
+	assert.Equal(t, newrelic.TimeRange{Since: until.Add(-3 * time.Hour), Until: until}, cadenceTimeRange(newrelic.TimeRange{Since: longScenarioStart, Until: until}, 30))
+}
+
+func Test_countMissedCycles(t *testing.T) {
+	assert.Equal(t, 0, countMissedCycles(nil, 0))
+	assert.Equal(t, 0, countMissedCycles([]float64{0, 0, 0}, 0))
+	assert.Equal(t, 0, countMissedCycles([]float64{0, 1, 0}, 0))
+	assert.Equal(t, 0, countMissedCycles([]float64{0, 1, 1, 1, 1, 0}, 0))
+	// Jitter moving a data point to the next bucket is not a missed cycle.
+	assert.Equal(t, 0, countMissedCycles([]float64{1, 1, 0, 2, 1, 1}, 0))
+	// One cycle of the span is left for the jitter, so the first missed cycle only shows with others.
+	assert.Equal(t, 0, countMissedCycles([]float64{1, 1, 0, 1, 1, 1}, 0))
+	assert.Equal(t, 1, countMissedCycles([]float64{1, 1, 0, 1, 1, 0, 1, 1}, 0))
+	// Reporting every other cycle fills every bucket twice as wide as the resolution.
+	assert.Equal(t, 4, countMissedCycles([]float64{1, 0, 1, 0, 1, 0, 1, 0, 1, 0, 1}, 0))
+	assert.Equal(t, 0, countMissedCycles([]float64{1, 0, 1, 0, 1, 0, 1, 0, 1, 0, 1}, 1))
+}
+
+func TestMetricsTester_checkUnexpectedMetrics(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+	metricsTester := NewMetricsTester(clientMock{}, log, "")
+
+	entities := []spec.Entity{
+		{EntityType: "ENTITY-A", Metrics: []spec.Metric{{Name: "metric-A"}}},
+		{EntityType: "ENTITY-B", Metrics: []spec.Metric{{Name: "metric-B"}}},
+	}
+
+	tests := []struct {
+		name                   string
+		unexpectedMetrics      spec.UnexpectedMetrics
+		testMetrics            spec.TestMetrics
+		reportedMetrics        []string
+		metricEntityTypes      map[string][]string
+		numberOfErrorsExpected int
+	}{
+		{
+			name:                   "when only declared metrics are reported it shouldn't return errors",
+			unexpectedMetrics:      spec.UnexpectedMetrics{Mode: spec.UnexpectedMetricsFail},
+			reportedMetrics:        []string{"metric-A", "metric-B"},
+			numberOfErrorsExpected: 0,
+		},
+		{
+			name:                   "when undeclared metrics are reported in fail mode it should return one error per metric",
+			unexpectedMetrics:      spec.UnexpectedMetrics{Mode: spec.UnexpectedMetricsFail},
+			reportedMetrics:        []string{"metric-A", "metric-renamed", "metric-undocumented"},
+			numberOfErrorsExpected: 2,
+		},
+		{
+			name:                   "when undeclared metrics are reported in warn mode it shouldn't return errors",
+			unexpectedMetrics:      spec.UnexpectedMetrics{Mode: spec.UnexpectedMetricsWarn},
+			reportedMetrics:        []string{"metric-A", "metric-renamed"},
+			numberOfErrorsExpected: 0,
+		},
+		{
+			name:                   "when undeclared metrics are allowed it shouldn't return errors",
+			unexpectedMetrics:      spec.UnexpectedMetrics{Mode: spec.UnexpectedMetricsFail, Allow: []string{"nr_stats_metrics_total"}},
+			reportedMetrics:        []string{"metric-A", "nr_stats_metrics_total"},
+			numberOfErrorsExpected: 0,
+		},
+		{
+			name:              "when undeclared metrics are only reported by excepted entities it shouldn't return errors",
+			unexpectedMetrics: spec.UnexpectedMetrics{Mode: spec.UnexpectedMetricsFail},
+			testMetrics: spec.TestMetrics{
+				Exceptions: spec.Exceptions{ExceptEntities: []spec.Exception{{Name: "ENTITY-B"}}},
+			},
+			reportedMetrics:        []string{"metric-A", "metric-B-undeclared"},
+			metricEntityTypes:      map[string][]string{"metric-A": {"ENTITY-A"}, "metric-B-undeclared": {"ENTITY-B"}},
+			numberOfErrorsExpected: 0,
+		},
+		{
+			name:              "when undeclared metrics are also reported by other entities it should return an error",
+			unexpectedMetrics: spec.UnexpectedMetrics{Mode: spec.UnexpectedMetricsFail},
+			testMetrics: spec.TestMetrics{
+				Exceptions: spec.Exceptions{ExceptEntities: []spec.Exception{{Name: "ENTITY-B"}}},
+			},
+			reportedMetrics:        []string{"metric-shared-undeclared", "metric-untyped-undeclared"},
+			metricEntityTypes:      map[string][]string{"metric-shared-undeclared": {"ENTITY-A", "ENTITY-B"}},
+			numberOfErrorsExpected: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.testMetrics.UnexpectedMetrics = &tt.unexpectedMetrics
+			errors := metricsTester.checkUnexpectedMetrics(entities, tt.testMetrics, tt.reportedMetrics, tt.metricEntityTypes)
+			require.Equal(t, tt.numberOfErrorsExpected, len(errors))
+		})
+	}
+}
+
+func Test_unusedPatterns(t *testing.T) {
+	values := []string{"k8s.node.capacityCpuCores", "k8s.node.allocatableCpuCores"}
+	patterns := []string{"k8s.node.capacity*", "k8s.node.capacityAttachableVolumes*", "regex:k8s\\.node\\.allocatable.*", "regex:k8s\\.pod\\..*"}
+
+	assert.Equal(t, []string{"k8s.node.capacityAttachableVolumes*", "regex:k8s\\.pod\\..*"}, unusedPatterns(patterns, values))
+	assert.Nil(t, unusedPatterns(nil, values))
+}
+
+func TestMetricsTester_checkExceptions(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+	metricsTester := NewMetricsTester(clientMock{}, log, "")
+
+	entities := []spec.Entity{
+		{EntityType: "ENTITY-A", Metrics: []spec.Metric{{Name: "metric-A1"}, {Name: "metric-A2"}}},
+		{EntityType: "ENTITY-B", Metrics: []spec.Metric{{Name: "metric-B"}}},
+	}
+
+	tests := []struct {
+		name                   string
+		testMetrics            spec.TestMetrics
+		queriedMetrics         []string
+		numberOfErrorsExpected int
+	}{
+		{
+			name: "when exceptions are still needed and not expired it shouldn't return errors",
+			testMetrics: spec.TestMetrics{
+				StrictExceptions: true,
+				Exceptions: spec.Exceptions{
+					ExceptMetrics: []spec.Exception{{Name: "metric-A*", Until: "2999-12-31"}},
+				},
+			},
+			queriedMetrics:         []string{"metric-A1", "metric-B"},
+			numberOfErrorsExpected: 0,
+		},
+		{
+			name: "when exceptions are expired in strict mode it should return one error per exception",
+			testMetrics: spec.TestMetrics{
+				StrictExceptions: true,
+				Exceptions: spec.Exceptions{
+					ExceptEntities: []spec.Exception{{Name: "ENTITY-B", Until: "2000-01-01"}},
+					ExceptMetrics:  []spec.Exception{{Name: "metric-A2", Until: "2000-01-01", Reason: "not implemented"}},
+				},
+			},
+			queriedMetrics:         []string{"metric-A1"},
+			numberOfErrorsExpected: 2,
+		},
+		{
+			name: "when every metric excepted is reported in strict mode it should return an error",
+			testMetrics: spec.TestMetrics{
+				StrictExceptions: true,
+				Exceptions: spec.Exceptions{
+					ExceptMetrics: []spec.Exception{{Name: "metric-A*"}},
+				},
+			},
+			queriedMetrics:         []string{"metric-A1", "metric-A2", "metric-B"},
+			numberOfErrorsExpected: 1,
+		},
+		{
+			name: "when exceptions are expired or stale without strict mode it shouldn't return errors",
+			testMetrics: spec.TestMetrics{
+				Exceptions: spec.Exceptions{
+					ExceptMetrics: []spec.Exception{{Name: "metric-B", Until: "2000-01-01"}},
+				},
+			},
+			queriedMetrics:         []string{"metric-A1", "metric-A2", "metric-B"},
+			numberOfErrorsExpected: 0,
+		},
+	}
+
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			errors := metricsTester.checkMetrics(entities, tt.testMetrics, tt.queriedMetrics)
@@ -126,3 +652,141 @@ func TestMetricsTester_checkMetrics(t *testing.T) {
 		})
 	}
 }
+
+func TestMetricsTester_filterByVersion(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+	metricsTester := NewMetricsTester(clientMock{}, log, "")
+
+	entities := []spec.Entity{
+		{
+			EntityType: "ENTITY-A",
+			Metrics: []spec.Metric{
+				{Name: "metric-always"},
+				{Name: "metric-added", MinVersion: "4.5"},
+				{Name: "metric-removed", MaxVersion: "4.4.9"},
+			},
+		},
+	}
+
+	filtered, err := metricsTester.filterByVersion(entities, "4.4.1")
+	require.NoError(t, err)
+	assert.Equal(t, []spec.Metric{{Name: "metric-always"}, {Name: "metric-removed", MaxVersion: "4.4.9"}}, filtered[0].Metrics)
+
+	filtered, err = metricsTester.filterByVersion(entities, "4.5.0")
+	require.NoError(t, err)
+	assert.Equal(t, []spec.Metric{{Name: "metric-always"}, {Name: "metric-added", MinVersion: "4.5"}}, filtered[0].Metrics)
+
+	errors := metricsTester.checkMetrics(filtered, spec.TestMetrics{}, []string{"metric-always", "metric-added"})
+	assert.Equal(t, 0, len(errors))
+
+	_, err = metricsTester.filterByVersion(entities, "latest")
+	assert.ErrorIs(t, err, spec.ErrInvalidVersion)
+}
+
+func TestMetricsTester_checkValues(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+	metricsTester := NewMetricsTester(clientMock{}, log, "")
+
+	maxValue := 0.5
+	tests := []struct {
+		name                   string
+		metrics                []spec.Metric
+		testMetrics            spec.TestMetrics
+		numberOfErrorsExpected int
+	}{
+		{
+			name:                   "when the metric has no value rules it shouldn't return errors",
+			metrics:                []spec.Metric{{Name: "metric-A"}},
+			numberOfErrorsExpected: 0,
+		},
+		{
+			name:                   "when the values satisfy the rules of the spec file it shouldn't return errors",
+			metrics:                []spec.Metric{{Name: "metric-A", ValueRules: &spec.ValueRules{NonZero: true, MustChange: true}}},
+			numberOfErrorsExpected: 0,
+		},
+		{
+			name:                   "when the values break several rules it should return one error for each one",
+			metrics:                []spec.Metric{{Name: "metric-A", ValueRules: &spec.ValueRules{NonNegative: true, Max: &maxValue}}},
+			numberOfErrorsExpected: 2,
+		},
+		{
+			name:                   "when a metric is stuck for an entity it should return an error for that entity",
+			metrics:                []spec.Metric{{Name: stuckMetric, ValueRules: &spec.ValueRules{NonZero: true}}},
+			numberOfErrorsExpected: 1,
+		},
+		{
+			name:                   "when a metric has no numeric values it should return an error",
+			metrics:                []spec.Metric{{Name: nanMetric, ValueRules: &spec.ValueRules{NonNegative: true}}},
+			numberOfErrorsExpected: 1,
+		},
+		{
+			name:                   "when the values query fails it should return an error",
+			metrics:                []spec.Metric{{Name: errFindMetricValues, ValueRules: &spec.ValueRules{NonZero: true}}},
+			numberOfErrorsExpected: 1,
+		},
+		{
+			name:    "when the test defines rules for the metric they take precedence over the spec file",
+			metrics: []spec.Metric{{Name: "metric-A", ValueRules: &spec.ValueRules{NonNegative: true}}},
+			testMetrics: spec.TestMetrics{
+				ValueRules: map[string]spec.ValueRules{"metric-A": {MustChange: true}},
+			},
+			numberOfErrorsExpected: 0,
+		},
+		{
+			name:    "when the test defines rules for a metric without rules in the spec file they are evaluated",
+			metrics: []spec.Metric{{Name: stuckMetric}},
+			testMetrics: spec.TestMetrics{
+				ValueRules: map[string]spec.ValueRules{stuckMetric: {MustChange: true}},
+			},
+			numberOfErrorsExpected: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var queriedMetrics []string
+			for _, metric := range tt.metrics {
+				queriedMetrics = append(queriedMetrics, metric.Name)
+			}
+			entities := []spec.Entity{{EntityType: "ENTITY-A", Metrics: tt.metrics}}
+
+			errors := metricsTester.checkValues(entities, tt.testMetrics, queriedMetrics, "", "", newrelic.TimeRange{})
+			require.Equal(t, tt.numberOfErrorsExpected, len(errors))
+		})
+	}
+}
+
+func TestMetricsTester_warningsOnce(t *testing.T) {
+	log, hook := logtest.NewNullLogger()
+	metricsTester := NewMetricsTester(clientMock{}, log, "testdata")
+
+	tests := spec.Tests{Metrics: []spec.TestMetrics{{
+		Source: "powerdns.yml",
+		Exceptions: spec.Exceptions{
+			ExceptMetrics: []spec.Exception{
+				{Name: "powerdns_recursor_*"},
+				{Name: "powerdns_authoritative_deferred_*", Until: "2000-01-01"},
+			},
+		},
+	}}}
+
+	// The unused and the expired exceptions are logged before the retries.
+	metricsTester.Lint(tests)
+	require.Len(t, hook.AllEntries(), 2)
+	hook.Reset()
+
+	// The exception is also stale, every metric it excepts is reported, which is logged by the first attempt only.
+	for i := 0; i < 3; i++ {
+		metricsTester.Test(tests, "testKey", "e2e-tag", newrelic.TimeRange{})
+	}
+	var warnings []string
+	for _, entry := range hook.AllEntries() {
+		if entry.Level == logrus.WarnLevel {
+			warnings = append(warnings, entry.Message)
+		}
+	}
+	require.Len(t, warnings, 1)
+	assert.Contains(t, warnings[0], "is stale")
+}
@@ -2,17 +2,39 @@ package runtime
 
 import (
 	"errors"
+
+	"github.com/newrelic/newrelic-integration-e2e-action/internal/newrelic"
 	"github.com/newrelic/newrelic-integration-e2e-action/internal/spec"
 
 	"github.com/newrelic/newrelic-client-go/pkg/common"
 	"github.com/newrelic/newrelic-client-go/pkg/entities"
+	"github.com/newrelic/newrelic-client-go/pkg/nrdb"
 )
 
 const (
-	errFindEntityGUID   = "wrongEntitySample"
-	errFindEntityByGUID = "wrongEntityGUID"
-	correctEntityType   = "correctEntityType"
-	errNRQLQuery        = "wrongNRQLQuery"
+	errFindEntityGUID       = "wrongEntitySample"
+	errFindEntityByGUID     = "wrongEntityGUID"
+	correctEntityType       = "correctEntityType"
+	errNRQLQuery            = "wrongNRQLQuery"
+	errFindLogs             = "wrongLogsTag"
+	errFindMetricDimensions = "wrongDimensionsMetric"
+	errFindMetricTypes      = "wrongTypesMetric"
+	cumulativeCountMetric   = "cumulativeCountMetric"
+	mixedTypesMetric        = "mixedTypesMetric"
+	errFindMetricUnits      = "wrongUnitsMetric"
+	secondsUnitMetric       = "secondsUnitMetric"
+	errFindMetricTimeseries = "wrongTimeseriesMetric"
+	gapsMetric              = "gapsMetric"
+	everyOtherCycleMetric   = "everyOtherCycleMetric"
+	errFindMetricValues     = "wrongValuesMetric"
+	stuckMetric             = "stuckMetric"
+	nanMetric               = "nanMetric"
+	errFindRelatedEntities  = "wrongRelatedEntitiesSample"
+	duplicatedEntityGUID    = "duplicatedEntitySample"
+	noEntityGUID            = "noEntitySample"
+	nilEntityGUID           = "nilEntitySample"
+	otherTypeEntityGUID     = "otherTypeEntitySample"
+	otherEntityType         = "otherEntityType"
 )
 
 var (
@@ -21,13 +43,24 @@ var (
 
 type clientMock struct{}
 
-func (c clientMock) FindEntityGUIDs(sample, metricName, customTagKey, entityTag string, expectedNumber int) ([]common.EntityGUID, error) {
+func (c clientMock) FindEntityGUIDs(sample, metricName, _, customTagKey, entityTag string, _ newrelic.TimeRange, expectedNumber int) ([]common.EntityGUID, error) {
 	switch sample {
 	case errFindEntityGUID:
 		return nil, ErrorTest
 	case errFindEntityByGUID:
 		guid := common.EntityGUID(errFindEntityByGUID)
 		return []common.EntityGUID{guid}, nil
+	case errFindRelatedEntities:
+		guid := common.EntityGUID(errFindRelatedEntities)
+		return []common.EntityGUID{guid}, nil
+	case duplicatedEntityGUID:
+		return []common.EntityGUID{"AAAA", "BBBB"}, nil
+	case noEntityGUID:
+		return nil, newrelic.ErrNoResult
+	case nilEntityGUID:
+		return []common.EntityGUID{"AAAA", nilEntityGUID}, nil
+	case otherTypeEntityGUID:
+		return []common.EntityGUID{"AAAA", otherTypeEntityGUID}, nil
 	}
 
 	guid := common.EntityGUID("AAAA")
@@ -35,17 +68,114 @@ func (c clientMock) FindEntityGUIDs(sample, metricName, customTagKey, entityTag
 }
 
 func (c clientMock) FindEntityByGUID(guid *common.EntityGUID) (entities.EntityInterface, error) {
-	if *guid == errFindEntityByGUID {
+	entityType := correctEntityType
+	switch *guid {
+	case errFindEntityByGUID:
 		return nil, ErrorTest
+	case nilEntityGUID:
+		return nil, nil
+	case otherTypeEntityGUID:
+		entityType = otherEntityType
+	case errFindRelatedEntities:
+		entityType = errFindRelatedEntities
 	}
-	return entities.EntityInterface(&entities.GenericInfrastructureEntity{Type: correctEntityType}), nil
+	return entities.EntityInterface(&entities.GenericInfrastructureEntity{
+		Type:      entityType,
+		Name:      "localhost:8081",
+		Domain:    "INFRA",
+		Reporting: true,
+		Tags:      []entities.EntityTag{{Key: "clusterName", Values: []string{"e2e-cluster"}}},
+		GoldenMetrics: entities.EntityGoldenContextScopedGoldenMetrics{
+			Metrics: []entities.EntityGoldenMetric{{Name: "queries"}},
+		},
+	}), nil
 }
 
-func (c clientMock) FindEntityMetrics(sample, customTagKey, entityTag string) ([]string, error) {
+func (c clientMock) FindEntityMetrics(sample, customTagKey, entityTag string, _ newrelic.TimeRange) ([]string, error) {
 	return []string{"powerdns_authoritative_deferred_cache_actions"}, nil
 }
 
-func (c clientMock) NRQLQuery(query, customTagKey, entityTag string, errorExpected bool, expectedResults []spec.TestNRQLExpectedResult) error {
+func (c clientMock) FindMetricNames(customTagKey, entityTag string, _ newrelic.TimeRange) ([]string, error) {
+	return []string{
+		"powerdns_authoritative_deferred_cache_actions",
+		"powerdns_authoritative_latency_seconds_bucket",
+		"powerdns_authoritative_latency_seconds_sum",
+		"powerdns_authoritative_latency_seconds_count",
+		"powerdns_authoritative_response_seconds",
+		"powerdns_authoritative_response_seconds_sum",
+	}, nil
+}
+
+func (c clientMock) FindMetricEntityTypes(customTagKey, entityTag string, _ newrelic.TimeRange) (map[string][]string, error) {
+	return map[string][]string{"powerdns_authoritative_deferred_cache_actions": {"POWERDNS_AUTHORITATIVE"}}, nil
+}
+
+func (c clientMock) FindMetricDimensions(metricName, customTagKey, entityTag string, _ newrelic.TimeRange) ([]string, error) {
+	switch metricName {
+	case errFindMetricDimensions:
+		return nil, ErrorTest
+	}
+	return []string{"metricName", "testKey", "proto", "type"}, nil
+}
+
+func (c clientMock) FindMetricTypes(metricName, customTagKey, entityTag string, _ newrelic.TimeRange) ([]string, error) {
+	switch metricName {
+	case errFindMetricTypes:
+		return nil, ErrorTest
+	case cumulativeCountMetric:
+		return []string{"cumulativeCount"}, nil
+	case mixedTypesMetric:
+		return []string{"gauge", "count"}, nil
+	}
+	return []string{"gauge"}, nil
+}
+
+func (c clientMock) FindMetricUnits(metricName, customTagKey, entityTag string, _ newrelic.TimeRange) ([]string, error) {
+	switch metricName {
+	case errFindMetricUnits:
+		return nil, ErrorTest
+	case secondsUnitMetric:
+		return []string{"seconds"}, nil
+	}
+	return nil, nil
+}
+
+func (c clientMock) FindMetricTimeseries(metricName string, bucketSeconds int, customTagKey, entityTag string, _ newrelic.TimeRange) (map[string][]float64, error) {
+	switch metricName {
+	case errFindMetricTimeseries:
+		return nil, ErrorTest
+	case gapsMetric:
+		return map[string][]float64{
+			"entity-1": {0, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1},
+			"entity-2": {0, 1, 1, 0, 1, 0, 0, 1, 1, 1, 1},
+		}, nil
+	case everyOtherCycleMetric:
+		return map[string][]float64{
+			"entity-1": {0, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1},
+			"entity-2": {0, 1, 0, 1, 0, 1, 0, 1, 0, 1, 0, 1, 0, 1},
+		}, nil
+	}
+	// The jitter of the data points leaves some buckets empty and others with two data points.
+	return map[string][]float64{
+		"entity-1": {0, 1, 1, 2, 0, 1, 1, 1, 0},
+		"entity-2": {1, 1, 1, 1, 1, 1},
+	}, nil
+}
+
+func (c clientMock) FindMetricValues(metricName, customTagKey, entityTag string, _ newrelic.TimeRange) ([]newrelic.MetricValues, error) {
+	zero, one, negative := 0.0, 1.0, -1.0
+	switch metricName {
+	case errFindMetricValues:
+		return nil, ErrorTest
+	case stuckMetric:
+		return []newrelic.MetricValues{{Entity: "entity-1", Min: &zero, Max: &zero}, {Entity: "entity-2", Min: &one, Max: &one}}, nil
+	case nanMetric:
+		return []newrelic.MetricValues{{Entity: "entity-1"}}, nil
+	}
+	return []newrelic.MetricValues{{Entity: "entity-1", Min: &negative, Max: &one}}, nil
+}
+
+func (c clientMock) NRQLQuery(query, customTagKey, entityTag string, _ newrelic.TimeRange, errorExpected bool, expectedResults []spec.TestNRQLExpectedResult, timeseries *spec.TestNRQLTimeseries) error {
 	if query == errNRQLQuery && !errorExpected {
 		return ErrorTest
 	}
@@ -54,3 +184,49 @@ func (c clientMock) NRQLQuery(query, customTagKey, entityTag string, errorExpect
 	}
 	return nil
 }
+
+func (c clientMock) FindLogs(customTagKey, entityTag string, _ newrelic.TimeRange) ([]nrdb.NRDBResult, error) {
+	if entityTag == errFindLogs {
+		return nil, ErrorTest
+	}
+	return []nrdb.NRDBResult{
+		{"message": "starting integration nri-powerdns", "logtype": "integration", "hostname": "e2e-host"},
+		{"message": "integration exited with error: connection refused", "logtype": "integration", "hostname": "e2e-host"},
+		{"message": "GET /metrics 200", "logtype": "access", "status": 200},
+		{"logtype": "access", "status": 404},
+	}, nil
+}
+
+func (c clientMock) FindRelatedEntities(guid common.EntityGUID) ([]newrelic.Relationship, error) {
+	if guid == errFindRelatedEntities {
+		return nil, ErrorTest
+	}
+	return []newrelic.Relationship{
+		{Type: "CONTAINS", SourceGUID: guid, SourceType: "KAFKABROKER", TargetGUID: "topic-1", TargetType: "KAFKATOPIC"},
+		{Type: "CONTAINS", SourceGUID: guid, SourceType: "KAFKABROKER", TargetGUID: "topic-2", TargetType: "KAFKATOPIC"},
+		{Type: "HOSTS", SourceGUID: "host", SourceType: "HOST", TargetGUID: guid, TargetType: "KAFKABROKER"},
+	}, nil
+}
+
+// nrqlValues are the results of the queries returning a single row with a `value` key.
+var nrqlValues = map[string]interface{}{
+	"partitions bytes": 300.0,
+	"topic bytes":      300.0,
+	"brokers":          3.0,
+	"entities":         3.0,
+	"requests":         150.0,
+	"state":            "up",
+}
+
+func (c clientMock) NRQLResults(query, customTagKey, entityTag string, _ newrelic.TimeRange) ([]nrdb.NRDBResult, error) {
+	if query == errNRQLQuery {
+		return nil, ErrorTest
+	}
+	if value, ok := nrqlValues[query]; ok {
+		return []nrdb.NRDBResult{{"value": value}}, nil
+	}
+	return []nrdb.NRDBResult{
+		{"facet": "orders", "topic": "orders", "count": 10.0, "timestamp": 1660000000.0, "entity.guid": "MXxJTkZSQXxOQXwx", customTagKey: entityTag},
+		{"facet": "payments", "topic": "payments", "count": 3.0, "timestamp": 1660000000.0, "entity.guid": "MXxJTkZSQXxOQXwy", customTagKey: entityTag},
+	}, nil
+}
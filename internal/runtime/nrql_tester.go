@@ -2,31 +2,107 @@ package runtime
 
 import (
 	"fmt"
+	"strings"
 
+	"github.com/newrelic/newrelic-client-go/pkg/nrdb"
 	"github.com/newrelic/newrelic-integration-e2e-action/internal/newrelic"
 	"github.com/newrelic/newrelic-integration-e2e-action/internal/spec"
 	"github.com/sirupsen/logrus"
 )
 
 type NRQLTester struct {
-	nrClient newrelic.Client
-	logger   *logrus.Logger
+	nrClient        newrelic.Client
+	logger          *logrus.Logger
+	specParentDir   string
+	updateSnapshots bool
 }
 
-func NewNRQLTester(nrClient newrelic.Client, logger *logrus.Logger) NRQLTester {
+func NewNRQLTester(nrClient newrelic.Client, logger *logrus.Logger, specParentDir string, updateSnapshots bool) NRQLTester {
 	return NRQLTester{
-		nrClient: nrClient,
-		logger:   logger,
+		nrClient:        nrClient,
+		logger:          logger,
+		specParentDir:   specParentDir,
+		updateSnapshots: updateSnapshots,
 	}
 }
 
-func (nt NRQLTester) Test(tests spec.Tests, customTagKey, customTagValue string) []error {
+func (nt NRQLTester) Test(tests spec.Tests, customTagKey, customTagValue string, timeRange newrelic.TimeRange) []error {
 	var errors []error
 	for _, nrql := range tests.NRQLs {
-		testErr := nt.nrClient.NRQLQuery(nrql.Query, customTagKey, customTagValue, nrql.ErrorExpected, nrql.ExpectedResults)
-		if testErr != nil {
-			errors = append(errors, fmt.Errorf("%w", testErr))
+		if err := nt.testNRQL(nrql, customTagKey, customTagValue, timeRange); err != nil {
+			errors = append(errors, err)
 		}
 	}
 	return errors
 }
+
+// testNRQL checks the results of the query, querying them once when they are also evaluated by an assert
+// expression or compared with a snapshot.
+func (nt NRQLTester) testNRQL(nrql spec.TestNRQL, customTagKey, customTagValue string, timeRange newrelic.TimeRange) error {
+	if nrql.Assert == "" && nrql.Snapshot == nil {
+		return nt.nrClient.NRQLQuery(nrql.Query, customTagKey, customTagValue, timeRange, nrql.ErrorExpected, nrql.ExpectedResults, nrql.Timeseries)
+	}
+
+	results, err := nt.nrClient.NRQLResults(nrql.Query, customTagKey, customTagValue, timeRange)
+	if err != nil {
+		return err
+	}
+
+	if nrql.Assert != "" {
+		err = testAssertion(nrql, results)
+	} else {
+		err = newrelic.CheckNRQLResults(nrql.Query, results, nrql.ErrorExpected, nrql.ExpectedResults, nrql.Timeseries)
+	}
+	if err != nil || nrql.Snapshot == nil {
+		return err
+	}
+	return nt.testSnapshot(nrql, results, customTagValue)
+}
+
+// testAssertion evaluates the assert expression of the test against the results of the query.
+func testAssertion(nrql spec.TestNRQL, results []nrdb.NRDBResult) error {
+	// The expression is compiled once when parsing the spec file.
+	assertion := nrql.Assertion
+	if assertion == nil {
+		return fmt.Errorf("%w: assert of %s was not compiled", spec.ErrInvalidAssertion, nrql.Query)
+	}
+
+	ok, err := assertion.Evaluate(results)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %s", newrelic.ErrAssertionFailure, nrql.Query, err)
+	}
+	if !ok {
+		return fmt.Errorf("%w - %s is false for results %v: %s", newrelic.ErrAssertionFailure, assertion, results, nrql.Query)
+	}
+	return nil
+}
+
+// testSnapshot compares the results of the query with its snapshot, or writes them to the snapshot
+// when running with update_snapshots. It is called once the other assertions of the test pass, and empty
+// results are never recorded, so a retry does not record the partial data of an attempt.
+func (nt NRQLTester) testSnapshot(nrql spec.TestNRQL, results []nrdb.NRDBResult, customTagValue string) error {
+	if len(results) == 0 {
+		return fmt.Errorf("%w for snapshot %s: %s", newrelic.ErrNoResult, nrql.Snapshot.Name, nrql.Query)
+	}
+
+	actual, err := normalizeSnapshotRows(results, nrql.Snapshot.Mask, customTagValue)
+	if err != nil {
+		return fmt.Errorf("normalizing results of snapshot %s: %w", nrql.Snapshot.Name, err)
+	}
+
+	path := snapshotPath(nt.specParentDir, nrql.Snapshot.Name)
+	if nt.updateSnapshots {
+		nt.logger.Infof("updating snapshot %s", path)
+		return writeSnapshot(path, actual)
+	}
+
+	expected, err := readSnapshot(path)
+	if err != nil {
+		return fmt.Errorf("reading snapshot %s, run with update_snapshots to record it: %w", nrql.Snapshot.Name, err)
+	}
+
+	if diff := diffSnapshot(expected, actual, nrql.Snapshot.Tolerance); len(diff) > 0 {
+		return fmt.Errorf("%w %s: %s\n%s", ErrSnapshotMismatch, path, nrql.Query, strings.Join(diff, "\n"))
+	}
+	return nil
+}
@@ -4,36 +4,38 @@ import (
 	"io/ioutil"
 	"testing"
 
+	"github.com/newrelic/newrelic-integration-e2e-action/internal/newrelic"
 	"github.com/newrelic/newrelic-integration-e2e-action/internal/spec"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNRQLTester_Test(t *testing.T) {
 	log := logrus.New()
 	log.SetOutput(ioutil.Discard)
-	nrqlTester := NewNRQLTester(clientMock{}, log)
+	nrqlTester := NewNRQLTester(clientMock{}, log, "", false)
 
 	inputTests := spec.Tests{NRQLs: []spec.TestNRQL{
 		{Query: errNRQLQuery},
 		{Query: "a-correct-query"},
 	}}
 
-	errors := nrqlTester.Test(inputTests, "", "")
+	errors := nrqlTester.Test(inputTests, "", "", newrelic.TimeRange{})
 	assert.Equal(t, 1, len(errors))
 }
 
 func TestNRQLTester_Test_error(t *testing.T) {
 	log := logrus.New()
 	log.SetOutput(ioutil.Discard)
-	nrqlTester := NewNRQLTester(clientMock{}, log)
+	nrqlTester := NewNRQLTester(clientMock{}, log, "", false)
 
 	inputTests := spec.Tests{NRQLs: []spec.TestNRQL{
 		{Query: errNRQLQuery, ErrorExpected: true},
 		{Query: "a-correct-query"},
 	}}
 
-	errors := nrqlTester.Test(inputTests, "", "")
+	errors := nrqlTester.Test(inputTests, "", "", newrelic.TimeRange{})
 	assert.Equal(t, 0, len(errors))
 
 	inputTests = spec.Tests{NRQLs: []spec.TestNRQL{
@@ -41,6 +43,39 @@ func TestNRQLTester_Test_error(t *testing.T) {
 		{Query: "a-correct-query", ErrorExpected: true},
 	}}
 
-	errors = nrqlTester.Test(inputTests, "", "")
+	errors = nrqlTester.Test(inputTests, "", "", newrelic.TimeRange{})
 	assert.Equal(t, 2, len(errors))
 }
+
+func TestNRQLTester_Test_assert(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+	nrqlTester := NewNRQLTester(clientMock{}, log, "", false)
+
+	inputTests := spec.Tests{NRQLs: []spec.TestNRQL{
+		assertNRQL(t, "a-correct-query", `results.size() == 2 && results.exists(r, r.topic == "orders" && r.count >= 10)`),
+		assertNRQL(t, "brokers", `results[0].value == 3`),
+	}}
+
+	errors := nrqlTester.Test(inputTests, "", "", newrelic.TimeRange{})
+	assert.Equal(t, 0, len(errors))
+
+	inputTests = spec.Tests{NRQLs: []spec.TestNRQL{
+		assertNRQL(t, "a-correct-query", `results.all(r, r.count >= 10)`),
+		assertNRQL(t, "a-correct-query", `results[0].missing == 1`),
+		assertNRQL(t, errNRQLQuery, `results.size() == 0`),
+		{Query: "a-correct-query", Assert: `results.size() == 2`},
+	}}
+
+	// The last assertion is not compiled since it wasn't validated.
+	errors = nrqlTester.Test(inputTests, "", "", newrelic.TimeRange{})
+	assert.Equal(t, 4, len(errors))
+}
+
+// assertNRQL returns a NRQL test with its assertion compiled, as done when parsing the spec file.
+func assertNRQL(t *testing.T, query, expr string) spec.TestNRQL {
+	t.Helper()
+	assertion, err := spec.CompileAssertion(expr)
+	require.NoError(t, err)
+	return spec.TestNRQL{Query: query, Assert: expr, Assertion: assertion}
+}
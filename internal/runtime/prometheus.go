@@ -0,0 +1,168 @@
+package runtime
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/newrelic/newrelic-integration-e2e-action/internal/spec"
+)
+
+var prometheusMetricNameRegex = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+// prometheusSeriesSuffixes are the suffixes of the series of a metric family, like the buckets of histograms.
+var prometheusSeriesSuffixes = []string{"_bucket", "_sum", "_count", "_total", "_created", "_gsum", "_gcount", "_info"}
+
+// scrapedMetrics are the metrics exposed by a Prometheus exporter.
+type scrapedMetrics struct {
+	// Series are the names of the samples, like `http_request_duration_seconds_bucket` for histograms.
+	Series map[string]bool
+	// Families are the names of the metrics declared by `# TYPE` lines, like `http_request_duration_seconds`,
+	// with the names of their series in order of appearance.
+	Families map[string][]string
+}
+
+// SeriesOf returns the names of the series of a metric family, or the name itself if it is the name of a
+// series without family. It is empty if nothing is exposed with that name.
+func (sm scrapedMetrics) SeriesOf(name string) []string {
+	if series, ok := sm.Families[name]; ok {
+		return series
+	}
+	if sm.Series[name] {
+		return []string{name}
+	}
+	return nil
+}
+
+// Matches returns true if any series or metric family matches the pattern.
+func (sm scrapedMetrics) Matches(pattern string) bool {
+	for name := range sm.Series {
+		if spec.MatchesPattern(pattern, name) {
+			return true
+		}
+	}
+	for name := range sm.Families {
+		if spec.MatchesPattern(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePrometheusText parses the Prometheus text exposition format, returning an error for the first
+// malformed sample line.
+func parsePrometheusText(r io.Reader) (scrapedMetrics, error) {
+	scraped := scrapedMetrics{
+		Series:   map[string]bool{},
+		Families: map[string][]string{},
+	}
+	// family is the last family declared, whose series are the samples following its `# TYPE` line.
+	family := ""
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), 16*1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "#") {
+			fields := strings.Fields(line)
+			if len(fields) >= 3 && fields[1] == "TYPE" {
+				family = fields[2]
+				if _, ok := scraped.Families[family]; !ok {
+					scraped.Families[family] = nil
+				}
+			}
+			continue
+		}
+
+		name, err := parsePrometheusSample(line)
+		if err != nil {
+			return scraped, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		if !scraped.Series[name] && isFamilySeries(family, name) {
+			scraped.Families[family] = append(scraped.Families[family], name)
+		}
+		scraped.Series[name] = true
+	}
+	return scraped, scanner.Err()
+}
+
+// isFamilySeries returns true if the series is named after the family, with or without a series suffix.
+func isFamilySeries(family, name string) bool {
+	if family == "" {
+		return false
+	}
+	if name == family {
+		return true
+	}
+	for _, suffix := range prometheusSeriesSuffixes {
+		if name == family+suffix {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePrometheusSample returns the name of the series of a sample line: `name{labels} value [timestamp]`.
+func parsePrometheusSample(line string) (string, error) {
+	nameEnd := strings.IndexAny(line, "{ \t")
+	if nameEnd < 0 {
+		return "", fmt.Errorf("missing value in %q", line)
+	}
+
+	name := line[:nameEnd]
+	if !prometheusMetricNameRegex.MatchString(name) {
+		return "", fmt.Errorf("invalid metric name %q", name)
+	}
+
+	rest := line[nameEnd:]
+	if strings.HasPrefix(rest, "{") {
+		labelsEnd := closingBraceIndex(rest)
+		if labelsEnd < 0 {
+			return "", fmt.Errorf("unclosed labels in %q", line)
+		}
+		rest = rest[labelsEnd+1:]
+	}
+
+	fields := strings.Fields(rest)
+	if len(fields) == 0 || len(fields) > 2 {
+		return "", fmt.Errorf("expected a value and an optional timestamp in %q", line)
+	}
+	if _, err := strconv.ParseFloat(fields[0], 64); err != nil {
+		return "", fmt.Errorf("invalid value %q of %s", fields[0], name)
+	}
+	if len(fields) == 2 {
+		if _, err := strconv.ParseInt(fields[1], 10, 64); err != nil {
+			return "", fmt.Errorf("invalid timestamp %q of %s", fields[1], name)
+		}
+	}
+	return name, nil
+}
+
+// closingBraceIndex returns the index of the brace closing the labels, skipping the quoted label values.
+func closingBraceIndex(labels string) int {
+	quoted := false
+	for i := 0; i < len(labels); i++ {
+		switch labels[i] {
+		case '\\':
+			if quoted {
+				i++
+			}
+		case '"':
+			quoted = !quoted
+		case '}':
+			if !quoted {
+				return i
+			}
+		}
+	}
+	return -1
+}
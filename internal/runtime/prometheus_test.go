@@ -0,0 +1,94 @@
+package runtime
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testExporterMetrics = `# HELP powerdns_authoritative_deferred_cache_actions Deferred cache actions.
+# TYPE powerdns_authoritative_deferred_cache_actions counter
+powerdns_authoritative_deferred_cache_actions{type="lookup"} 12
+powerdns_authoritative_deferred_cache_actions{type="inserts"} 3 1646128800000
+# TYPE powerdns_authoritative_queries_total counter
+powerdns_authoritative_queries_total{proto="udp4",label="with \"quotes\" and {braces}"} 1.5e+03
+
+# TYPE powerdns_authoritative_latency_seconds histogram
+powerdns_authoritative_latency_seconds_bucket{le="+Inf"} 4
+powerdns_authoritative_latency_seconds_sum 0.25
+powerdns_authoritative_latency_seconds_count 4
+# TYPE powerdns_authoritative_response_seconds summary
+powerdns_authoritative_response_seconds{quantile="0.5"} 0.01
+powerdns_authoritative_response_seconds_sum 0.25
+powerdns_authoritative_response_seconds_count 4
+powerdns_authoritative_exporter_json_parse_failures NaN
+`
+
+func Test_parsePrometheusText(t *testing.T) {
+	scraped, err := parsePrometheusText(strings.NewReader(testExporterMetrics))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"powerdns_authoritative_deferred_cache_actions"}, scraped.SeriesOf("powerdns_authoritative_deferred_cache_actions"))
+	assert.Equal(t, []string{"powerdns_authoritative_queries_total"}, scraped.SeriesOf("powerdns_authoritative_queries_total"))
+	assert.Equal(t, []string{
+		"powerdns_authoritative_latency_seconds_bucket",
+		"powerdns_authoritative_latency_seconds_sum",
+		"powerdns_authoritative_latency_seconds_count",
+	}, scraped.SeriesOf("powerdns_authoritative_latency_seconds"))
+	assert.Equal(t, []string{"powerdns_authoritative_latency_seconds_bucket"}, scraped.SeriesOf("powerdns_authoritative_latency_seconds_bucket"))
+	assert.Equal(t, []string{"powerdns_authoritative_exporter_json_parse_failures"}, scraped.SeriesOf("powerdns_authoritative_exporter_json_parse_failures"))
+	assert.Equal(t, []string{
+		"powerdns_authoritative_response_seconds",
+		"powerdns_authoritative_response_seconds_sum",
+		"powerdns_authoritative_response_seconds_count",
+	}, scraped.SeriesOf("powerdns_authoritative_response_seconds"))
+	assert.Empty(t, scraped.SeriesOf("powerdns_authoritative_up"))
+
+	assert.True(t, scraped.Matches("powerdns_authoritative_latency_*"))
+	assert.True(t, scraped.Matches(`regex:.*_seconds_(sum|count)`))
+	assert.False(t, scraped.Matches("powerdns_recursor_*"))
+}
+
+func Test_parsePrometheusTextErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		text          string
+		errorExpected string
+	}{
+		{
+			name:          "when the sample has no value it should return an error",
+			text:          "powerdns_authoritative_up",
+			errorExpected: "line 1: missing value",
+		},
+		{
+			name:          "when the metric name is invalid it should return an error",
+			text:          "# TYPE up gauge\npowerdns-authoritative-up 1",
+			errorExpected: `line 2: invalid metric name "powerdns-authoritative-up"`,
+		},
+		{
+			name:          "when the labels are not closed it should return an error",
+			text:          `powerdns_authoritative_up{instance="localhost"} 1` + "\n" + `powerdns_authoritative_up{instance="local}host" 1`,
+			errorExpected: "line 2: unclosed labels",
+		},
+		{
+			name:          "when the value is not a number it should return an error",
+			text:          `powerdns_authoritative_up{instance="localhost"} up`,
+			errorExpected: `line 1: invalid value "up"`,
+		},
+		{
+			name:          "when the timestamp is not an integer it should return an error",
+			text:          `powerdns_authoritative_up 1 now`,
+			errorExpected: `line 1: invalid timestamp "now"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parsePrometheusText(strings.NewReader(tt.text))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errorExpected)
+		})
+	}
+}
@@ -0,0 +1,306 @@
+package runtime
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	protocolV3 = "3"
+	protocolV4 = "4"
+
+	// eventTypeAttribute is the attribute holding the event type of the samples of protocol v3.
+	eventTypeAttribute = "event_type"
+
+	// hostEntityType is the entity type of the data emitted without entity, which belongs to the host
+	// running the integration.
+	hostEntityType = "HOST"
+)
+
+// protocolV4MetricTypes are the metric types of protocol v4, mapped to whether their value is a number.
+var protocolV4MetricTypes = map[string]bool{
+	"gauge":                true,
+	"count":                true,
+	"cumulative-count":     true,
+	"rate":                 true,
+	"cumulative-rate":      true,
+	"summary":              false,
+	"prometheus-summary":   false,
+	"prometheus-histogram": false,
+}
+
+// protocolOutput holds the entities and metrics emitted by an integration, used to cross-check them with
+// the metrics source file.
+type protocolOutput struct {
+	// Entities are the emitted entities described as "type:name".
+	Entities []string
+	// EntityTypes are the metrics emitted by the entities of each type, keyed by the type normalized as the
+	// entityType of the metrics source files.
+	EntityTypes map[string]*entityOutput
+}
+
+// entityOutput holds the metrics emitted by the entities of a type.
+type entityOutput struct {
+	// Metrics are the names of the dimensional metrics of protocol v4.
+	Metrics map[string]bool
+	// SampleAttributes are the attributes of the samples of protocol v3 by event type.
+	SampleAttributes map[string]map[string]bool
+}
+
+// entityType returns the metrics emitted by the entities of the type, adding them to the output if needed.
+func (o *protocolOutput) entityType(entityType string) *entityOutput {
+	eo, ok := o.EntityTypes[entityType]
+	if !ok {
+		eo = &entityOutput{
+			Metrics:          map[string]bool{},
+			SampleAttributes: map[string]map[string]bool{},
+		}
+		o.EntityTypes[entityType] = eo
+	}
+	return eo
+}
+
+// normalizeEntityType returns the type of an emitted entity as the entityType of the metrics source files,
+// i.e. `fake-server` is `FAKE_SERVER`.
+func normalizeEntityType(entityType string) string {
+	return strings.ToUpper(strings.ReplaceAll(entityType, "-", "_"))
+}
+
+// parseProtocolOutput validates the payloads written by an integration to stdout, one JSON per line,
+// against the integrations SDK protocol v3 and v4. It returns every violation found.
+func parseProtocolOutput(stdout []byte) (protocolOutput, []error) {
+	output := protocolOutput{
+		EntityTypes: map[string]*entityOutput{},
+	}
+
+	var errors []error
+	payloads := 0
+	scanner := bufio.NewScanner(bytes.NewReader(stdout))
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), 64*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		payloads++
+
+		var payload map[string]interface{}
+		if err := json.Unmarshal(line, &payload); err != nil {
+			errors = append(errors, fmt.Errorf("payload %d is not a JSON object: %w", payloads, err))
+			continue
+		}
+
+		v := protocolValidator{path: fmt.Sprintf("payload %d", payloads), output: &output}
+		v.validatePayload(payload)
+		errors = append(errors, v.errors...)
+	}
+	if err := scanner.Err(); err != nil {
+		errors = append(errors, fmt.Errorf("reading payloads: %w", err))
+	}
+
+	if payloads == 0 {
+		errors = append(errors, fmt.Errorf("no payload written to stdout"))
+	}
+	sort.Strings(output.Entities)
+	return output, errors
+}
+
+// protocolValidator accumulates the violations of a payload, identified by their path in the JSON.
+type protocolValidator struct {
+	path   string
+	output *protocolOutput
+	errors []error
+}
+
+func (v *protocolValidator) errorf(path, format string, args ...interface{}) {
+	v.errors = append(v.errors, fmt.Errorf("%s: %s: %s", v.path, path, fmt.Sprintf(format, args...)))
+}
+
+func (v *protocolValidator) validatePayload(payload map[string]interface{}) {
+	version := protocolVersion(payload["protocol_version"])
+	switch version {
+	case protocolV3:
+		v.requireString(payload, "name", "name")
+		v.requireString(payload, "integration_version", "integration_version")
+	case protocolV4:
+		integration, ok := v.requireObject(payload, "integration", "integration")
+		if ok {
+			v.requireString(integration, "name", "integration.name")
+			v.requireString(integration, "version", "integration.version")
+		}
+	default:
+		v.errorf("protocol_version", "unsupported version %v, expected %s or %s", payload["protocol_version"], protocolV3, protocolV4)
+		return
+	}
+
+	data, ok := v.requireArray(payload, "data", "data")
+	if !ok {
+		return
+	}
+	for i, item := range data {
+		path := fmt.Sprintf("data[%d]", i)
+		dataset, ok := item.(map[string]interface{})
+		if !ok {
+			v.errorf(path, "expected an object")
+			continue
+		}
+		v.validateDataset(version, dataset, path)
+	}
+}
+
+func (v *protocolValidator) validateDataset(version string, dataset map[string]interface{}, path string) {
+	// The entity can be omitted for the data of the host running the integration. The metrics of an invalid
+	// entity are validated but not recorded.
+	var emitted *entityOutput
+	if _, ok := dataset["entity"]; ok {
+		entity, ok := v.requireObject(dataset, "entity", path+".entity")
+		if ok {
+			name, nameOk := v.requireString(entity, "name", path+".entity.name")
+			entityType, typeOk := v.requireString(entity, "type", path+".entity.type")
+			if nameOk && typeOk {
+				v.output.Entities = append(v.output.Entities, entityType+":"+name)
+				emitted = v.output.entityType(normalizeEntityType(entityType))
+			}
+		}
+	} else {
+		emitted = v.output.entityType(hostEntityType)
+	}
+	if emitted == nil {
+		emitted = &entityOutput{Metrics: map[string]bool{}, SampleAttributes: map[string]map[string]bool{}}
+	}
+
+	if _, ok := dataset["inventory"]; ok {
+		v.requireObject(dataset, "inventory", path+".inventory")
+	}
+
+	if _, ok := dataset["events"]; ok {
+		events, _ := v.requireArray(dataset, "events", path+".events")
+		for i, item := range events {
+			eventPath := fmt.Sprintf("%s.events[%d]", path, i)
+			event, ok := item.(map[string]interface{})
+			if !ok {
+				v.errorf(eventPath, "expected an object")
+				continue
+			}
+			v.requireString(event, "summary", eventPath+".summary")
+		}
+	}
+
+	if _, ok := dataset["metrics"]; !ok {
+		return
+	}
+	metrics, _ := v.requireArray(dataset, "metrics", path+".metrics")
+	for i, item := range metrics {
+		metricPath := fmt.Sprintf("%s.metrics[%d]", path, i)
+		metric, ok := item.(map[string]interface{})
+		if !ok {
+			v.errorf(metricPath, "expected an object")
+			continue
+		}
+		if version == protocolV3 {
+			v.validateSample(emitted, metric, metricPath)
+		} else {
+			v.validateMetric(emitted, metric, metricPath)
+		}
+	}
+}
+
+// validateSample validates a sample of protocol v3, a set of attributes of an event type.
+func (v *protocolValidator) validateSample(emitted *entityOutput, sample map[string]interface{}, path string) {
+	eventType, ok := v.requireString(sample, eventTypeAttribute, path+"."+eventTypeAttribute)
+	if !ok {
+		return
+	}
+
+	attributes, ok := emitted.SampleAttributes[eventType]
+	if !ok {
+		attributes = map[string]bool{}
+		emitted.SampleAttributes[eventType] = attributes
+	}
+	for attribute := range sample {
+		attributes[attribute] = true
+	}
+}
+
+// validateMetric validates a dimensional metric of protocol v4.
+func (v *protocolValidator) validateMetric(emitted *entityOutput, metric map[string]interface{}, path string) {
+	name, nameOk := v.requireString(metric, "name", path+".name")
+	metricType, typeOk := v.requireString(metric, "type", path+".type")
+	if nameOk {
+		emitted.Metrics[name] = true
+	}
+
+	if _, ok := metric["attributes"]; ok {
+		v.requireObject(metric, "attributes", path+".attributes")
+	}
+
+	if !typeOk {
+		return
+	}
+	numeric, ok := protocolV4MetricTypes[metricType]
+	if !ok {
+		v.errorf(path+".type", "unknown metric type %q", metricType)
+		return
+	}
+
+	value, ok := metric["value"]
+	if !ok {
+		v.errorf(path+".value", "missing value")
+		return
+	}
+	switch value.(type) {
+	case float64:
+		if !numeric {
+			v.errorf(path+".value", "expected an object for a %s metric", metricType)
+		}
+	case map[string]interface{}:
+		if numeric {
+			v.errorf(path+".value", "expected a number for a %s metric", metricType)
+		}
+	default:
+		v.errorf(path+".value", "expected a number or an object, got %T", value)
+	}
+}
+
+func (v *protocolValidator) requireString(object map[string]interface{}, key, path string) (string, bool) {
+	value, ok := object[key].(string)
+	if !ok || value == "" {
+		v.errorf(path, "expected a non empty string")
+		return "", false
+	}
+	return value, true
+}
+
+func (v *protocolValidator) requireObject(object map[string]interface{}, key, path string) (map[string]interface{}, bool) {
+	value, ok := object[key].(map[string]interface{})
+	if !ok {
+		v.errorf(path, "expected an object")
+		return nil, false
+	}
+	return value, true
+}
+
+func (v *protocolValidator) requireArray(object map[string]interface{}, key, path string) ([]interface{}, bool) {
+	value, ok := object[key].([]interface{})
+	if !ok {
+		v.errorf(path, "expected an array")
+		return nil, false
+	}
+	return value, true
+}
+
+// protocolVersion returns the version of the payload, which is written as a string or a number.
+func protocolVersion(version interface{}) string {
+	switch version := version.(type) {
+	case string:
+		return strings.TrimSpace(version)
+	case float64:
+		return fmt.Sprintf("%g", version)
+	default:
+		return ""
+	}
+}
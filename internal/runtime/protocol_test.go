@@ -0,0 +1,93 @@
+package runtime
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseProtocolOutput(t *testing.T) {
+	tests := []struct {
+		name           string
+		stdout         string
+		errorsExpected []string
+	}{
+		{
+			name:   "when the payload is a valid protocol v4 payload it should not return errors",
+			stdout: `{"protocol_version":"4","integration":{"name":"com.newrelic.fake","version":"0.1.0"},"data":[{"entity":{"name":"db-1","type":"FAKE_SERVER"},"metrics":[{"name":"fake.up","type":"gauge","value":1},{"name":"fake.latency","type":"summary","value":{"count":1,"sum":2,"min":2,"max":2}}]}]}`,
+		},
+		{
+			name: "when the payloads are valid protocol v3 payloads it should not return errors",
+			stdout: `{"name":"com.newrelic.fake","protocol_version":"3","integration_version":"0.1.0","data":[{"entity":{"name":"db-1","type":"fake-server","id_attributes":[]},"metrics":[{"event_type":"FakeSample","fake.up":1}],"inventory":{},"events":[{"summary":"restarted","category":"notifications"}]}]}
+
+{"name":"com.newrelic.fake","protocol_version":3,"integration_version":"0.1.0","data":[{"metrics":[{"event_type":"FakeHostSample","fake.load":1}]}]}`,
+		},
+		{
+			name:           "when there is no payload it should return an error",
+			stdout:         "\n",
+			errorsExpected: []string{"no payload written to stdout"},
+		},
+		{
+			name:           "when the payload is not JSON it should return an error",
+			stdout:         `level=info msg="starting"`,
+			errorsExpected: []string{"payload 1 is not a JSON object"},
+		},
+		{
+			name:           "when the protocol version is not supported it should return an error",
+			stdout:         `{"protocol_version":"2","data":[]}`,
+			errorsExpected: []string{`payload 1: protocol_version: unsupported version 2`},
+		},
+		{
+			name:   "when the protocol v4 payload is malformed it should return every violation",
+			stdout: `{"protocol_version":"4","integration":{"name":"com.newrelic.fake"},"data":[{"entity":{"name":"db-1"},"metrics":[{"name":"fake.up","type":"gauge","value":"1"},{"type":"histogram","value":1},{"name":"fake.latency","type":"summary","value":1}],"events":[{}]}]}`,
+			errorsExpected: []string{
+				"payload 1: integration.version: expected a non empty string",
+				"payload 1: data[0].entity.type: expected a non empty string",
+				"payload 1: data[0].events[0].summary: expected a non empty string",
+				"payload 1: data[0].metrics[0].value: expected a number or an object, got string",
+				"payload 1: data[0].metrics[1].name: expected a non empty string",
+				`payload 1: data[0].metrics[1].type: unknown metric type "histogram"`,
+				"payload 1: data[0].metrics[2].value: expected an object for a summary metric",
+			},
+		},
+		{
+			name:   "when the protocol v3 payload is malformed it should return every violation",
+			stdout: `{"protocol_version":"3","integration_version":"0.1.0","data":[{"metrics":[{"fake.up":1}],"inventory":[]}]}`,
+			errorsExpected: []string{
+				"payload 1: name: expected a non empty string",
+				"payload 1: data[0].inventory: expected an object",
+				"payload 1: data[0].metrics[0].event_type: expected a non empty string",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, errors := parseProtocolOutput([]byte(tt.stdout))
+			require.Equal(t, len(tt.errorsExpected), len(errors), "errors: %v", errors)
+			for i, expected := range tt.errorsExpected {
+				assert.Contains(t, errors[i].Error(), expected)
+			}
+		})
+	}
+}
+
+func Test_parseProtocolOutput_output(t *testing.T) {
+	stdout := `{"protocol_version":"4","integration":{"name":"com.newrelic.fake","version":"0.1.0"},"data":[{"entity":{"name":"db-2","type":"FAKE_SERVER"},"metrics":[{"name":"fake.up","type":"gauge","value":1}]},{"entity":{"name":"db-1","type":"FAKE_SERVER"},"metrics":[{"name":"fake.queries","type":"count","value":1}]}]}
+{"name":"com.newrelic.fake","protocol_version":"3","integration_version":"0.1.0","data":[{"metrics":[{"event_type":"FakeSample","fake.load":1}]}]}`
+
+	output, errors := parseProtocolOutput([]byte(stdout))
+	require.Empty(t, errors)
+	assert.Equal(t, []string{"FAKE_SERVER:db-1", "FAKE_SERVER:db-2"}, output.Entities)
+	assert.Equal(t, map[string]*entityOutput{
+		"FAKE_SERVER": {
+			Metrics:          map[string]bool{"fake.up": true, "fake.queries": true},
+			SampleAttributes: map[string]map[string]bool{},
+		},
+		hostEntityType: {
+			Metrics:          map[string]bool{},
+			SampleAttributes: map[string]map[string]bool{"FakeSample": {"event_type": true, "fake.load": true}},
+		},
+	}, output.EntityTypes)
+}
@@ -0,0 +1,237 @@
+package runtime
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/newrelic/newrelic-integration-e2e-action/internal/newrelic"
+	"github.com/newrelic/newrelic-integration-e2e-action/internal/spec"
+	"github.com/sirupsen/logrus"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// configPathEnv is the variable the agent uses to pass the path of the file with the `config` of the integration.
+const configPathEnv = "CONFIG_PATH"
+
+// ProtocolTester executes the integrations locally and validates their output, no data is sent nor
+// queried from New Relic.
+type ProtocolTester struct {
+	logger        *logrus.Logger
+	specParentDir string
+}
+
+func NewProtocolTester(logger *logrus.Logger, specParentDir string) ProtocolTester {
+	return ProtocolTester{
+		logger:        logger,
+		specParentDir: specParentDir,
+	}
+}
+
+// Test executes the integrations when it runs, so their output always belongs to the time range of the tests.
+func (pt ProtocolTester) Test(tests spec.Tests, _, customTagValue string, _ newrelic.TimeRange) []error {
+	var errors []error
+	for _, tp := range tests.Protocol {
+		stdout, err := pt.executeIntegration(tp, customTagValue)
+		if err != nil {
+			errors = append(errors, fmt.Errorf("executing integration %s: %w", tp.Integration, err))
+			continue
+		}
+
+		output, protocolErrors := parseProtocolOutput(stdout)
+		for _, protocolErr := range protocolErrors {
+			errors = append(errors, fmt.Errorf("validating output of integration %s: %w", tp.Integration, protocolErr))
+		}
+		pt.logger.Debugf("integration %s emitted entities: %s", tp.Integration, strings.Join(output.Entities, ", "))
+
+		if tp.Source == "" {
+			continue
+		}
+
+		content, err := ioutil.ReadFile(filepath.Join(pt.specParentDir, tp.Source))
+		if err != nil {
+			errors = append(errors, fmt.Errorf("reading metrics source file: %w", err))
+			continue
+		}
+		metrics, err := spec.ParseMetricsFile(content)
+		if err != nil {
+			errors = append(errors, fmt.Errorf("unmarshaling metrics source file: %w", err))
+			continue
+		}
+
+		errors = append(errors, checkProtocolMetrics(metrics.Entities, tp, output)...)
+	}
+	return errors
+}
+
+// executeIntegration runs the binary of the integration with its config and env, the way the agent does,
+// and returns what it writes to stdout.
+func (pt ProtocolTester) executeIntegration(tp spec.TestProtocol, scenarioTag string) ([]byte, error) {
+	integration := tp.ScenarioIntegration
+	if integration == nil {
+		return nil, fmt.Errorf("%w: integration %q not found", spec.ErrInvalidProtocolConfig, tp.Integration)
+	}
+
+	// The timeout has already been validated when parsing the spec file.
+	timeout, _ := tp.TimeoutDuration()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	binaryPath, err := filepath.Abs(filepath.Join(pt.specParentDir, integration.BinaryPath))
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.CommandContext(ctx, binaryPath)
+	cmd.Dir = pt.specParentDir
+	cmd.Env = append(os.Environ(), "SCENARIO_TAG="+scenarioTag)
+	for key, value := range integration.Env {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%v", key, value))
+	}
+
+	if len(integration.Config) > 0 {
+		configPath, err := writeIntegrationConfig(integration.Config)
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(configPath)
+		cmd.Env = append(cmd.Env, configPathEnv+"="+configPath)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	pt.logger.Debugf("execute integration %s: %s", tp.Integration, binaryPath)
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("timed out after %s: %w", timeout, ctx.Err())
+		}
+		return nil, fmt.Errorf("%w, stderr: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	pt.logger.Debugf("integration %s stderr: %s", tp.Integration, stderr.String())
+
+	return stdout.Bytes(), nil
+}
+
+func writeIntegrationConfig(config map[string]interface{}) (string, error) {
+	content, err := yaml.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("marshaling integration config: %w", err)
+	}
+
+	file, err := ioutil.TempFile("", "e2e-integration-config-*.yml")
+	if err != nil {
+		return "", fmt.Errorf("creating integration config file: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(content); err != nil {
+		return "", fmt.Errorf("writing integration config file: %w", err)
+	}
+	return file.Name(), nil
+}
+
+// checkProtocolMetrics checks that the entity types declared in the source file are the ones emitted by the
+// integration, that the metrics of each entity type are emitted by its entities and, for protocol v4, that
+// every metric emitted by an entity is declared in its entity type. Samples of protocol v3 are checked
+// through the legacy names of the metrics.
+func checkProtocolMetrics(entities []spec.Entity, tp spec.TestProtocol, output protocolOutput) []error {
+	var errors []error
+	declaredTypes := map[string]bool{}
+	for _, entity := range entities {
+		entityType := normalizeEntityType(entity.EntityType)
+		declaredTypes[entityType] = true
+		if matchesAnyException(entity.EntityType, tp.ExceptEntities) {
+			continue
+		}
+
+		emitted, ok := output.EntityTypes[entityType]
+		if !ok {
+			errors = append(errors, fmt.Errorf("finding entities of type %s in the output", entity.EntityType))
+			continue
+		}
+
+		var missing []string
+		declared := map[string]bool{}
+		for _, metric := range entity.Metrics {
+			declared[metric.Name] = true
+			if matchesAnyException(metric.Name, tp.ExceptMetrics) {
+				continue
+			}
+			if !emitted.emits(metric) {
+				missing = append(missing, metric.Name)
+			}
+		}
+		if len(missing) > 0 {
+			errors = append(errors, fmt.Errorf("finding metrics of entity %s in the output: %s", entity.EntityType, strings.Join(missing, ", ")))
+		}
+
+		var undeclared []string
+		for name := range emitted.Metrics {
+			if !declared[name] && !matchesAnyException(name, tp.ExceptMetrics) {
+				undeclared = append(undeclared, name)
+			}
+		}
+		if len(undeclared) > 0 {
+			sort.Strings(undeclared)
+			errors = append(errors, fmt.Errorf("metrics emitted by entity %s but not declared in %s: %s", entity.EntityType, tp.Source, strings.Join(undeclared, ", ")))
+		}
+	}
+
+	var undeclaredTypes []string
+	for entityType := range output.EntityTypes {
+		if !declaredTypes[entityType] && !matchesAnyException(entityType, tp.ExceptEntities) {
+			undeclaredTypes = append(undeclaredTypes, entityType)
+		}
+	}
+	if len(undeclaredTypes) > 0 {
+		sort.Strings(undeclaredTypes)
+		errors = append(errors, fmt.Errorf("entity types emitted but not declared in %s: %s", tp.Source, strings.Join(undeclaredTypes, ", ")))
+	}
+	return errors
+}
+
+// emits returns true if the metric is emitted as a dimensional metric or, when it has migration information,
+// as all its legacy names in a sample of its legacy event type.
+func (eo entityOutput) emits(metric spec.Metric) bool {
+	if eo.Metrics[metric.Name] {
+		return true
+	}
+
+	mi := metric.MigrationInformation
+	if mi == nil || len(mi.LegacyNames) == 0 {
+		return false
+	}
+	for eventType, attributes := range eo.SampleAttributes {
+		if !mi.IsLegacyEventType(eventType) {
+			continue
+		}
+		emitted := true
+		for _, legacyName := range mi.LegacyNames {
+			if !attributes[legacyName] {
+				emitted = false
+				break
+			}
+		}
+		if emitted {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAnyException(name string, exceptions []spec.Exception) bool {
+	for _, exception := range exceptions {
+		if exception.Matches(name) {
+			return true
+		}
+	}
+	return false
+}
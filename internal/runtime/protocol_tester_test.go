@@ -0,0 +1,150 @@
+package runtime
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/newrelic/newrelic-integration-e2e-action/internal/newrelic"
+	"github.com/newrelic/newrelic-integration-e2e-action/internal/spec"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProtocolTester_Test(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+	protocolTester := NewProtocolTester(log, "testdata/protocol")
+
+	fakeIntegration := &spec.Integration{
+		Name:       "nri-fake",
+		BinaryPath: "nri-fake",
+		Config:     map[string]interface{}{"hostname": "db-1"},
+	}
+
+	tests := []struct {
+		name           string
+		protocol       spec.TestProtocol
+		errorsExpected []string
+	}{
+		{
+			name:     "when the output is valid and there is no source it should not return errors",
+			protocol: spec.TestProtocol{Integration: "nri-fake", ScenarioIntegration: fakeIntegration},
+		},
+		{
+			name:           "when a declared metric is not emitted it should return an error",
+			protocol:       spec.TestProtocol{Integration: "nri-fake", Source: "fake.yml", ScenarioIntegration: fakeIntegration},
+			errorsExpected: []string{"finding metrics of entity FAKE_SERVER in the output: fake.errors"},
+		},
+		{
+			name: "when the missing metric is excepted it should not return errors",
+			protocol: spec.TestProtocol{
+				Integration:         "nri-fake",
+				Source:              "fake.yml",
+				ScenarioIntegration: fakeIntegration,
+				Exceptions:          spec.Exceptions{ExceptMetrics: []spec.Exception{{Name: "fake.errors"}}},
+			},
+		},
+		{
+			name: "when the integration exits with an error it should return its stderr",
+			protocol: spec.TestProtocol{
+				Integration: "nri-fake",
+				ScenarioIntegration: &spec.Integration{
+					Name:       "nri-fake",
+					BinaryPath: "nri-fake",
+					Env:        map[string]interface{}{"FAKE_ERROR": "cannot connect to db-1"},
+				},
+			},
+			errorsExpected: []string{"executing integration nri-fake: exit status 1, stderr: cannot connect to db-1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errors := protocolTester.Test(spec.Tests{Protocol: []spec.TestProtocol{tt.protocol}}, "testKey", "e2e-tag", newrelic.TimeRange{})
+			require.Equal(t, len(tt.errorsExpected), len(errors), "errors: %v", errors)
+			for i, expected := range tt.errorsExpected {
+				assert.Contains(t, errors[i].Error(), expected)
+			}
+		})
+	}
+}
+
+func Test_checkProtocolMetrics(t *testing.T) {
+	entities := []spec.Entity{
+		{
+			EntityType: "FAKE_SERVER",
+			Metrics: []spec.Metric{
+				{Name: "fake.up"},
+				{
+					Name: "fake.load",
+					MigrationInformation: &spec.MigrationInformation{
+						LegacyEventType: "FakeSample",
+						LegacyNames:     []string{"fake.load", "fake.loadAverage"},
+					},
+				},
+			},
+		},
+	}
+
+	fakeServer := func(eo entityOutput) protocolOutput {
+		return protocolOutput{EntityTypes: map[string]*entityOutput{"FAKE_SERVER": &eo}}
+	}
+
+	tests := []struct {
+		name           string
+		output         protocolOutput
+		errorsExpected []string
+	}{
+		{
+			name:   "when the metrics are emitted as dimensional metrics it should not return errors",
+			output: fakeServer(entityOutput{Metrics: map[string]bool{"fake.up": true, "fake.load": true}}),
+		},
+		{
+			name: "when the metrics are emitted as legacy samples it should not return errors",
+			output: fakeServer(entityOutput{
+				Metrics:          map[string]bool{"fake.up": true},
+				SampleAttributes: map[string]map[string]bool{"FakeSample": {"fake.load": true, "fake.loadAverage": true}},
+			}),
+		},
+		{
+			name: "when a legacy name is missing it should return an error",
+			output: fakeServer(entityOutput{
+				Metrics:          map[string]bool{"fake.up": true},
+				SampleAttributes: map[string]map[string]bool{"FakeSample": {"fake.load": true}},
+			}),
+			errorsExpected: []string{"finding metrics of entity FAKE_SERVER in the output: fake.load"},
+		},
+		{
+			name:           "when undeclared metrics are emitted it should return an error",
+			output:         fakeServer(entityOutput{Metrics: map[string]bool{"fake.up": true, "fake.load": true, "fake.b": true, "fake.a": true}}),
+			errorsExpected: []string{"metrics emitted by entity FAKE_SERVER but not declared in fake.yml: fake.a, fake.b"},
+		},
+		{
+			name: "when the metrics are emitted by an entity of another type it should return an error",
+			output: protocolOutput{EntityTypes: map[string]*entityOutput{
+				"FAKE_SERVER": {Metrics: map[string]bool{"fake.up": true}},
+				"FAKE_DB":     {Metrics: map[string]bool{"fake.load": true}},
+			}},
+			errorsExpected: []string{
+				"finding metrics of entity FAKE_SERVER in the output: fake.load",
+				"entity types emitted but not declared in fake.yml: FAKE_DB",
+			},
+		},
+		{
+			name:           "when the declared entity type is not emitted it should return an error",
+			output:         protocolOutput{EntityTypes: map[string]*entityOutput{hostEntityType: {Metrics: map[string]bool{"fake.up": true}}}},
+			errorsExpected: []string{"finding entities of type FAKE_SERVER in the output", "entity types emitted but not declared in fake.yml: HOST"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errors := checkProtocolMetrics(entities, spec.TestProtocol{Source: "fake.yml"}, tt.output)
+			require.Equal(t, len(tt.errorsExpected), len(errors), "errors: %v", errors)
+			for i, expected := range tt.errorsExpected {
+				assert.Contains(t, errors[i].Error(), expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package runtime
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/newrelic/newrelic-client-go/pkg/common"
+	"github.com/newrelic/newrelic-integration-e2e-action/internal/newrelic"
+	"github.com/newrelic/newrelic-integration-e2e-action/internal/spec"
+	"github.com/sirupsen/logrus"
+)
+
+type RelationshipsTester struct {
+	nrClient newrelic.Client
+	logger   *logrus.Logger
+}
+
+func NewRelationshipsTester(nrClient newrelic.Client, logger *logrus.Logger) RelationshipsTester {
+	return RelationshipsTester{
+		nrClient: nrClient,
+		logger:   logger,
+	}
+}
+
+func (rt RelationshipsTester) Test(tests spec.Tests, customTagKey, customTagValue string, timeRange newrelic.TimeRange) []error {
+	var errors []error
+	for _, tr := range tests.Relationships {
+		// By default if not notified, we expect at least one related entity
+		if tr.MinCount == 0 {
+			tr.MinCount = 1
+		}
+
+		// The entities test has already been validated to exist when parsing the spec file.
+		en, _ := spec.FindTestEntity(tests.Entities, tr.EntityType)
+		minCount, _ := en.CountBounds()
+
+		guids, err := rt.nrClient.FindEntityGUIDs(en.DataType, en.MetricName, en.Attribute, customTagKey, customTagValue, timeRange, minCount)
+		if err != nil {
+			errors = append(errors, fmt.Errorf("finding entity guid of %s: %w", tr.EntityType, err))
+			continue
+		}
+
+		guids, err = rt.entitiesOfType(guids, tr.EntityType)
+		if err != nil {
+			errors = append(errors, err)
+			continue
+		}
+		if len(guids) == 0 {
+			errors = append(errors, fmt.Errorf("%w: no entities of type %s to check their relationships", newrelic.ErrNoResult, tr.EntityType))
+			continue
+		}
+
+		for _, guid := range guids {
+			relationships, err := rt.nrClient.FindRelatedEntities(guid)
+			if err != nil {
+				errors = append(errors, fmt.Errorf("finding related entities of %s %s: %w", tr.EntityType, guid, err))
+				continue
+			}
+			rt.logger.Debugf("found %d relationships for %s %s", len(relationships), tr.EntityType, guid)
+
+			matches := matchingRelationships(relationships, guid, tr)
+			if len(matches) < tr.MinCount {
+				errors = append(errors, fmt.Errorf("finding %s related to %s %s: got %d, expected at least %d, found: [%s]",
+					describeRelationshipTest(tr), tr.EntityType, guid, len(matches), tr.MinCount, describeRelationships(relationships)))
+			}
+		}
+	}
+	return errors
+}
+
+// entitiesOfType returns the GUIDs resolved to entities of the type, ignoring the ones without entity and the
+// entities of other types as the entities test does.
+func (rt RelationshipsTester) entitiesOfType(guids []common.EntityGUID, entityType string) ([]common.EntityGUID, error) {
+	var matching []common.EntityGUID
+	for _, guid := range guids {
+		entity, err := rt.nrClient.FindEntityByGUID(&guid)
+		if err != nil {
+			return nil, fmt.Errorf("finding entity %s of %s: %w", guid, entityType, err)
+		}
+		if entity == nil || entity.GetType() != entityType {
+			rt.logger.Debugf("ignoring entity %s not resolved to type %s", guid, entityType)
+			continue
+		}
+		matching = append(matching, guid)
+	}
+	return matching, nil
+}
+
+// matchingRelationships returns the relationships linking the entity, in the direction of the test, to an
+// entity of the target type with the type of the test.
+func matchingRelationships(relationships []newrelic.Relationship, guid common.EntityGUID, tr spec.TestRelationship) []newrelic.Relationship {
+	var matches []newrelic.Relationship
+	for _, r := range relationships {
+		if tr.Type != "" && r.Type != tr.Type {
+			continue
+		}
+		outgoing := r.SourceGUID == guid && r.TargetType == tr.TargetType
+		incoming := r.TargetGUID == guid && r.SourceType == tr.TargetType
+		switch tr.Direction {
+		case spec.DirectionIncoming:
+			if !incoming {
+				continue
+			}
+		case spec.DirectionAny:
+			if !outgoing && !incoming {
+				continue
+			}
+		default:
+			if !outgoing {
+				continue
+			}
+		}
+		matches = append(matches, r)
+	}
+	return matches
+}
+
+func describeRelationshipTest(tr spec.TestRelationship) string {
+	description := tr.TargetType
+	if tr.Type != "" {
+		description = fmt.Sprintf("%s with relationship %s", description, tr.Type)
+	}
+	if tr.Direction != "" {
+		description = fmt.Sprintf("%s (%s)", description, tr.Direction)
+	}
+	return description
+}
+
+// describeRelationships lists the relationships found, used to make failures easier to debug.
+func describeRelationships(relationships []newrelic.Relationship) string {
+	described := make([]string, 0, len(relationships))
+	for _, r := range relationships {
+		described = append(described, fmt.Sprintf("%s %s-%s->%s %s", r.SourceType, r.SourceGUID, r.Type, r.TargetType, r.TargetGUID))
+	}
+	return strings.Join(described, ", ")
+}
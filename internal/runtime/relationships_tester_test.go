@@ -0,0 +1,113 @@
+package runtime
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/newrelic/newrelic-integration-e2e-action/internal/newrelic"
+	"github.com/newrelic/newrelic-integration-e2e-action/internal/spec"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRelationshipsTester_Test(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+	relationshipsTester := NewRelationshipsTester(clientMock{}, log)
+
+	entities := []spec.TestEntity{
+		{Type: correctEntityType, DataType: "Metric", MetricName: "kafka_broker_up"},
+		{Type: "WRONG_GUID", DataType: errFindEntityGUID},
+		{Type: errFindRelatedEntities, DataType: errFindRelatedEntities},
+		{Type: otherEntityType, DataType: otherTypeEntityGUID},
+		{Type: "UNRESOLVED", DataType: nilEntityGUID},
+		{Type: "WRONG_ENTITY", DataType: errFindEntityByGUID},
+	}
+
+	tests := []struct {
+		name                   string
+		relationships          []spec.TestRelationship
+		numberOfErrorsExpected int
+	}{
+		{
+			name:                   "when there are no relationships tests it shouldn't return errors",
+			numberOfErrorsExpected: 0,
+		},
+		{
+			name: "when the entity has the expected related entities it shouldn't return errors",
+			relationships: []spec.TestRelationship{
+				{EntityType: correctEntityType, Type: "CONTAINS", TargetType: "KAFKATOPIC", MinCount: 2},
+				{EntityType: correctEntityType, TargetType: "KAFKATOPIC"},
+			},
+			numberOfErrorsExpected: 0,
+		},
+		{
+			name: "when the entity has less related entities than expected it should return an error",
+			relationships: []spec.TestRelationship{
+				{EntityType: correctEntityType, Type: "CONTAINS", TargetType: "KAFKATOPIC", MinCount: 3},
+			},
+			numberOfErrorsExpected: 1,
+		},
+		{
+			name: "when the relationship type or the target type are not matching it should return an error",
+			relationships: []spec.TestRelationship{
+				{EntityType: correctEntityType, Type: "CALLS", TargetType: "KAFKATOPIC"},
+				{EntityType: correctEntityType, TargetType: "KAFKACONSUMER"},
+			},
+			numberOfErrorsExpected: 2,
+		},
+		{
+			name: "when the entity is only the target of the relationship it should return an error",
+			relationships: []spec.TestRelationship{
+				{EntityType: correctEntityType, Type: "HOSTS", TargetType: "HOST"},
+			},
+			numberOfErrorsExpected: 1,
+		},
+		{
+			name: "when the entity is the target of an incoming relationship it shouldn't return errors",
+			relationships: []spec.TestRelationship{
+				{EntityType: correctEntityType, Type: "HOSTS", TargetType: "HOST", Direction: spec.DirectionIncoming},
+				{EntityType: correctEntityType, TargetType: "HOST", Direction: spec.DirectionAny},
+				{EntityType: correctEntityType, TargetType: "KAFKATOPIC", MinCount: 2, Direction: spec.DirectionAny},
+			},
+			numberOfErrorsExpected: 0,
+		},
+		{
+			name: "when the entity is only the source of the relationship an incoming test should return an error",
+			relationships: []spec.TestRelationship{
+				{EntityType: correctEntityType, Type: "CONTAINS", TargetType: "KAFKATOPIC", Direction: spec.DirectionIncoming},
+			},
+			numberOfErrorsExpected: 1,
+		},
+		{
+			name: "when finding the entities or their relationships fails it should return an error",
+			relationships: []spec.TestRelationship{
+				{EntityType: "WRONG_GUID", TargetType: "KAFKATOPIC"},
+				{EntityType: errFindRelatedEntities, TargetType: "KAFKATOPIC"},
+				{EntityType: "WRONG_ENTITY", TargetType: "KAFKATOPIC"},
+			},
+			numberOfErrorsExpected: 3,
+		},
+		{
+			name: "when only the entities of the type are checked it shouldn't return errors",
+			relationships: []spec.TestRelationship{
+				{EntityType: otherEntityType, Type: "CONTAINS", TargetType: "KAFKATOPIC", MinCount: 2},
+			},
+			numberOfErrorsExpected: 0,
+		},
+		{
+			name: "when no entity resolves to the type it should return an error",
+			relationships: []spec.TestRelationship{
+				{EntityType: "UNRESOLVED", TargetType: "KAFKATOPIC"},
+			},
+			numberOfErrorsExpected: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errors := relationshipsTester.Test(spec.Tests{Entities: entities, Relationships: tt.relationships}, "", "", newrelic.TimeRange{})
+			assert.Equal(t, tt.numberOfErrorsExpected, len(errors))
+		})
+	}
+}
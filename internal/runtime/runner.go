@@ -1,6 +1,8 @@
 package runtime
 
 import (
+	"errors"
+	"fmt"
 	"math/rand"
 	"os"
 	"os/exec"
@@ -9,6 +11,7 @@ import (
 
 	e2e "github.com/newrelic/newrelic-integration-e2e-action/internal"
 	"github.com/newrelic/newrelic-integration-e2e-action/internal/agent"
+	"github.com/newrelic/newrelic-integration-e2e-action/internal/newrelic"
 	"github.com/newrelic/newrelic-integration-e2e-action/internal/runtime/logger"
 	"github.com/newrelic/newrelic-integration-e2e-action/internal/spec"
 	"github.com/newrelic/newrelic-integration-e2e-action/pkg/retrier"
@@ -16,14 +19,25 @@ import (
 )
 
 const (
-	dmTableName       = "Metric"
 	scenarioTagRuneNr = 5
 )
 
 var letterRunes = []rune("abcdefghijklmnopqrstuvwxyz")
 
+var (
+	ErrProtocolTestsFailed = errors.New("protocol tests failed")
+	ErrNoProtocolTests     = errors.New("no protocol tests found in the spec file")
+)
+
 type Tester interface {
-	Test(tests spec.Tests, customTagKey, customTagValue string) []error
+	// Test runs the tests against the data of the scenario reported within the time range.
+	Test(tests spec.Tests, customTagKey, customTagValue string, timeRange newrelic.TimeRange) []error
+}
+
+// Linter is implemented by the testers checking the definition of their tests, so its warnings are logged
+// once before the tests are retried.
+type Linter interface {
+	Lint(tests spec.Tests)
 }
 
 type Runner struct {
@@ -36,6 +50,8 @@ type Runner struct {
 	retryAfter    time.Duration
 	commitSha     string
 	scenarioTag   string
+	keepOnFailure bool
+	keptDir       string
 }
 
 func NewRunner(testers []Tester, settings e2e.Settings) *Runner {
@@ -55,6 +71,8 @@ func NewRunner(testers []Tester, settings e2e.Settings) *Runner {
 	if settings.AgentEnabled() {
 		agentInstance = agent.NewAgent(settings)
 	}
+	// The agent logs tester is created here since the agent is only known by the runner.
+	testers = append(testers, NewAgentLogsTester(agentInstance, settings.Logger()))
 
 	return &Runner{
 		agent:         agentInstance,
@@ -66,6 +84,8 @@ func NewRunner(testers []Tester, settings e2e.Settings) *Runner {
 		retryAfter:    retryAfter,
 		commitSha:     settings.CommitSha(),
 		scenarioTag:   settings.ScenarioTag(),
+		keepOnFailure: settings.KeepOnFailure(),
+		keptDir:       keptScenariosDir,
 	}
 }
 
@@ -78,6 +98,9 @@ func (r *Runner) Run() error {
 			return err
 		}
 
+		// The data of the scenario is reported once the agent is running.
+		scenarioStart := time.Now()
+
 		if r.agent != nil {
 			if err := r.agent.SetUp(scenario); err != nil {
 				return err
@@ -89,15 +112,33 @@ func (r *Runner) Run() error {
 		}
 
 		errAssertions := r.executeTests(spec.Tests{
-			NRQLs:    scenario.Tests.NRQLs,
-			Entities: scenario.Tests.Entities,
-			Metrics:  scenario.Tests.Metrics,
-		}, r.spec.CustomTestKey, scenarioTag)
+			NRQLs:         scenario.Tests.NRQLs,
+			Entities:      scenario.Tests.Entities,
+			Metrics:       scenario.Tests.Metrics,
+			Logs:          scenario.Tests.Logs,
+			Relationships: scenario.Tests.Relationships,
+			Compare:       scenario.Tests.Compare,
+			AgentLogs:     scenario.Tests.AgentLogs,
+			Protocol:      scenario.Tests.Protocol,
+			Exporters:     scenario.Tests.Exporters,
+		}, r.spec.CustomTestKey, scenarioTag, scenarioStart)
 
 		if err := r.executeOSCommands(scenario.Tests.Scripts, scenarioTag); err != nil {
+			if r.keepOnFailure {
+				return r.keepScenario(scenario, scenarioTag, err)
+			}
 			return err
 		}
 
+		if errAssertions == nil {
+			errAssertions = r.executeSteps(scenario.Steps, scenarioTag, scenarioStart)
+		}
+
+		// Teardown is skipped so the failing environment can be inspected, see Cleanup.
+		if errAssertions != nil && r.keepOnFailure {
+			return r.keepScenario(scenario, scenarioTag, errAssertions)
+		}
+
 		if err := r.executeOSCommands(scenario.After, scenarioTag); err != nil {
 			r.logger.Error(err)
 		}
@@ -116,19 +157,60 @@ func (r *Runner) Run() error {
 	return nil
 }
 
+// RunProtocol executes once the protocol tests of the scenarios and their steps, without running the before
+// commands nor the agent, and without querying New Relic, so the output of the integrations can be
+// validated offline.
+func (r *Runner) RunProtocol() error {
+	protocolTester := NewProtocolTester(r.logger, r.specParentDir)
+
+	executed := 0
+	for _, scenario := range r.spec.Scenarios {
+		protocol := scenario.Tests.Protocol
+		for _, step := range scenario.Steps {
+			if step.Tests != nil {
+				protocol = append(protocol, step.Tests.Protocol...)
+			}
+		}
+		if len(protocol) == 0 {
+			continue
+		}
+		executed += len(protocol)
+
+		scenarioTag := r.generateScenarioTag()
+		r.logger.Debugf("[scenario]: %s, [Tag]: %s", strings.TrimSpace(scenario.Description), scenarioTag)
+
+		errors := protocolTester.Test(spec.Tests{Protocol: protocol}, r.spec.CustomTestKey, scenarioTag, newrelic.TimeRange{})
+		if len(errors) > 0 {
+			for _, err := range errors {
+				r.logger.Error(err)
+			}
+			return fmt.Errorf("%w: scenario %q: %v", ErrProtocolTestsFailed, strings.TrimSpace(scenario.Description), errors)
+		}
+	}
+
+	if executed == 0 {
+		return ErrNoProtocolTests
+	}
+	return nil
+}
+
 func (r *Runner) executeOSCommands(statements []string, scenarioTag string) error {
+	return executeOSCommands(r.logger, r.spec.PlainLogs, r.specParentDir, statements, scenarioTag)
+}
+
+func executeOSCommands(log *logrus.Logger, plainLogs bool, dir string, statements []string, scenarioTag string) error {
 	// Create a logger for the executed commands.
 	var cmdLogger logger.CommandLogger
-	if r.spec.PlainLogs {
-		cmdLogger = logger.NewLogrusLogger(r.logger)
+	if plainLogs {
+		cmdLogger = logger.NewLogrusLogger(log)
 	} else {
 		cmdLogger = logger.NewGHALogger(os.Stderr)
 	}
 
 	for _, stmt := range statements {
-		r.logger.Debugf("execute command '%s' from path '%s'", stmt, r.specParentDir)
+		log.Debugf("execute command '%s' from path '%s'", stmt, dir)
 		cmd := exec.Command("bash", "-c", stmt)
-		cmd.Dir = r.specParentDir
+		cmd.Dir = dir
 		cmd.Env = os.Environ()
 		cmd.Env = append(cmd.Env, "SCENARIO_TAG="+scenarioTag)
 
@@ -146,10 +228,16 @@ func (r *Runner) executeOSCommands(statements []string, scenarioTag string) erro
 	return nil
 }
 
-func (r *Runner) executeTests(tests spec.Tests, customTestKey string, scenarioTag string) error {
+// executeTests runs the tests against the data reported from since until each attempt, so the retries take
+// into account the data reported meanwhile.
+func (r *Runner) executeTests(tests spec.Tests, customTestKey string, scenarioTag string, since time.Time) error {
 	for _, tester := range r.testers {
+		if linter, ok := tester.(Linter); ok {
+			linter.Lint(tests)
+		}
+
 		err := retrier.Retry(r.logger, r.retryAttempts, r.retryAfter, func() []error {
-			return tester.Test(tests, customTestKey, scenarioTag)
+			return tester.Test(tests, customTestKey, scenarioTag, newrelic.TimeRange{Since: since, Until: time.Now()})
 		})
 		if err != nil {
 			return err
@@ -158,6 +246,47 @@ func (r *Runner) executeTests(tests spec.Tests, customTestKey string, scenarioTa
 	return nil
 }
 
+// executeSteps runs the steps of a multi-step scenario in order. The tests of each step are limited to the
+// data reported since the end of the latest run step, or the start of the scenario, so they assert the
+// effect of the previous actions.
+func (r *Runner) executeSteps(steps []spec.Step, scenarioTag string, scenarioStart time.Time) error {
+	since := scenarioStart
+	for i, step := range steps {
+		r.logger.Debugf("[step %d]: %s", i, strings.TrimSpace(step.Description))
+
+		switch {
+		case len(step.Run) > 0:
+			if err := r.executeOSCommands(step.Run, scenarioTag); err != nil {
+				return fmt.Errorf("executing step %d: %w", i, err)
+			}
+			since = time.Now()
+		case step.Wait != "":
+			// The wait duration has already been validated when parsing the spec file.
+			wait, _ := step.WaitDuration()
+			time.Sleep(wait)
+		case step.Tests != nil:
+			if err := r.executeTests(spec.Tests{
+				NRQLs:         step.Tests.NRQLs,
+				Entities:      step.Tests.Entities,
+				Metrics:       step.Tests.Metrics,
+				Logs:          step.Tests.Logs,
+				Relationships: step.Tests.Relationships,
+				Compare:       step.Tests.Compare,
+				AgentLogs:     step.Tests.AgentLogs,
+				Protocol:      step.Tests.Protocol,
+				Exporters:     step.Tests.Exporters,
+			}, r.spec.CustomTestKey, scenarioTag, since); err != nil {
+				return fmt.Errorf("testing step %d: %w", i, err)
+			}
+
+			if err := r.executeOSCommands(step.Tests.Scripts, scenarioTag); err != nil {
+				return fmt.Errorf("executing scripts of step %d: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
 func (r *Runner) generateScenarioTag() string {
 	// if there is a customer defined scenario tag, just return it instead of generating a randome one.
 	if len(r.scenarioTag) > 0 {
@@ -3,8 +3,12 @@ package runtime
 import (
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/newrelic/newrelic-integration-e2e-action/internal/agent"
+	"github.com/newrelic/newrelic-integration-e2e-action/internal/newrelic"
 	"github.com/newrelic/newrelic-integration-e2e-action/internal/spec"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
@@ -15,6 +19,9 @@ type agentMock struct {
 	RunCalls    int
 	StopCalls   int
 	ScenarioTag string
+	AgentLogs   string
+	LogsErr     error
+	LogsCalls   int
 }
 
 func (a *agentMock) SetUp(_ spec.Scenario) error {
@@ -30,6 +37,21 @@ func (a *agentMock) Stop() error {
 	a.StopCalls++
 	return nil
 }
+func (a *agentMock) Environment() agent.Environment {
+	return agent.Environment{ContainerName: "agent"}
+}
+func (a *agentMock) Logs(_, _ time.Time) (string, error) {
+	a.LogsCalls++
+	return a.AgentLogs, a.LogsErr
+}
+
+type testerMock struct {
+	errors []error
+}
+
+func (tm testerMock) Test(_ spec.Tests, _, _ string, _ newrelic.TimeRange) []error {
+	return tm.errors
+}
 
 func TestRunner_Run(t *testing.T) {
 	const commitSha = "1234567A-long-commit-sha"
@@ -147,3 +169,218 @@ func TestRunner_RunWithTests(t *testing.T) {
 		})
 	}
 }
+
+func TestRunner_RunKeepOnFailure(t *testing.T) {
+	tests := []struct {
+		name          string
+		keepOnFailure bool
+		testErrors    []error
+		stopCalls     int
+		keptScenarios int
+	}{
+		{
+			name:          "when the scenario fails and keep on failure is enabled it should not tear down the scenario",
+			keepOnFailure: true,
+			testErrors:    []error{ErrorTest},
+			stopCalls:     0,
+			keptScenarios: 1,
+		},
+		{
+			name:          "when the scenario fails and keep on failure is disabled it should tear down the scenario",
+			keepOnFailure: false,
+			testErrors:    []error{ErrorTest},
+			stopCalls:     1,
+			keptScenarios: 0,
+		},
+		{
+			name:          "when the scenario succeeds and keep on failure is enabled it should tear down the scenario",
+			keepOnFailure: true,
+			testErrors:    nil,
+			stopCalls:     1,
+			keptScenarios: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := logrus.New()
+			log.SetOutput(ioutil.Discard)
+
+			keptDir := t.TempDir()
+			specDefinition := spec.Definition{
+				Scenarios:     []spec.Scenario{{Description: "scenario"}},
+				CustomTestKey: "testKey",
+			}
+
+			runner := Runner{
+				agent:         &agentMock{},
+				testers:       []Tester{testerMock{errors: tt.testErrors}},
+				logger:        log,
+				spec:          &specDefinition,
+				specParentDir: t.TempDir(),
+				retryAttempts: 1,
+				scenarioTag:   "e2e-tag",
+				keepOnFailure: tt.keepOnFailure,
+				keptDir:       keptDir,
+			}
+
+			err := runner.Run()
+			require.Equal(t, tt.testErrors != nil, err != nil)
+			require.Equal(t, tt.stopCalls, runner.agent.(*agentMock).StopCalls)
+
+			files, err := filepath.Glob(filepath.Join(keptDir, "*"))
+			require.NoError(t, err)
+			require.Equal(t, tt.keptScenarios, len(files))
+		})
+	}
+}
+
+type timeRangeRecorderTester struct {
+	timeRanges *[]newrelic.TimeRange
+}
+
+func (tt timeRangeRecorderTester) Test(_ spec.Tests, _, _ string, timeRange newrelic.TimeRange) []error {
+	*tt.timeRanges = append(*tt.timeRanges, timeRange)
+	return nil
+}
+
+func TestRunner_RunWithSteps(t *testing.T) {
+	tests := []struct {
+		name        string
+		steps       []spec.Step
+		expectError bool
+		// sinceRunStep tells for each execution of the tests if it is limited to the data since a run step
+		// instead of the start of the scenario.
+		sinceRunStep []bool
+	}{
+		{
+			name: "when the tests are not preceded by a run step they should be limited since the scenario start",
+			steps: []spec.Step{
+				{Wait: "1ms"},
+				{Tests: &spec.Tests{}},
+			},
+			sinceRunStep: []bool{false, false},
+		},
+		{
+			name: "when the tests are preceded by a run step they should be limited since the step",
+			steps: []spec.Step{
+				{Run: []string{"true"}},
+				{Wait: "1ms"},
+				{Tests: &spec.Tests{}},
+				{Run: []string{"true"}},
+				{Tests: &spec.Tests{}},
+			},
+			sinceRunStep: []bool{false, true, true},
+		},
+		{
+			name: "when a run step fails it should fail the scenario and skip the next steps",
+			steps: []spec.Step{
+				{Run: []string{"false"}},
+				{Tests: &spec.Tests{}},
+			},
+			expectError:  true,
+			sinceRunStep: []bool{false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := logrus.New()
+			log.SetOutput(ioutil.Discard)
+
+			specDefinition := spec.Definition{
+				Scenarios:     []spec.Scenario{{Description: "scenario", Steps: tt.steps}},
+				CustomTestKey: "testKey",
+			}
+
+			var timeRanges []newrelic.TimeRange
+			runner := Runner{
+				agent:         &agentMock{},
+				testers:       []Tester{timeRangeRecorderTester{timeRanges: &timeRanges}},
+				logger:        log,
+				spec:          &specDefinition,
+				specParentDir: t.TempDir(),
+				retryAttempts: 1,
+				scenarioTag:   "e2e-tag",
+			}
+
+			err := runner.Run()
+			require.Equal(t, tt.expectError, err != nil)
+			require.Equal(t, 1, runner.agent.(*agentMock).StopCalls)
+
+			require.Equal(t, len(tt.sinceRunStep), len(timeRanges))
+			scenarioStart := timeRanges[0].Since
+			for i, sinceRunStep := range tt.sinceRunStep {
+				require.False(t, timeRanges[i].Since.IsZero())
+				require.False(t, timeRanges[i].Until.Before(timeRanges[i].Since))
+				require.Equal(t, sinceRunStep, timeRanges[i].Since.After(scenarioStart))
+			}
+		})
+	}
+}
+
+func TestRunner_RunProtocol(t *testing.T) {
+	fakeIntegration := &spec.Integration{
+		Name:       "nri-fake",
+		BinaryPath: "nri-fake",
+		Config:     map[string]interface{}{"hostname": "db-1"},
+	}
+
+	tests := []struct {
+		name        string
+		scenarios   []spec.Scenario
+		expectedErr error
+	}{
+		{
+			name: "when the protocol tests pass it should not run the before commands nor the agent",
+			scenarios: []spec.Scenario{{
+				Description: "scenario",
+				Before:      []string{"false"},
+				Tests:       spec.Tests{Protocol: []spec.TestProtocol{{Integration: "nri-fake", ScenarioIntegration: fakeIntegration}}},
+				Steps: []spec.Step{
+					{Run: []string{"false"}},
+					{Tests: &spec.Tests{Protocol: []spec.TestProtocol{{Integration: "nri-fake", ScenarioIntegration: fakeIntegration}}}},
+				},
+			}},
+		},
+		{
+			name: "when a protocol test of a step fails it should return an error",
+			scenarios: []spec.Scenario{{
+				Description: "scenario",
+				Steps: []spec.Step{
+					{Tests: &spec.Tests{Protocol: []spec.TestProtocol{{Integration: "nri-fake", Source: "fake.yml", ScenarioIntegration: fakeIntegration}}}},
+				},
+			}},
+			expectedErr: ErrProtocolTestsFailed,
+		},
+		{
+			name:        "when there are no protocol tests it should return an error",
+			scenarios:   []spec.Scenario{{Description: "scenario", Tests: spec.Tests{NRQLs: []spec.TestNRQL{{Query: "a-correct-query"}}}}},
+			expectedErr: ErrNoProtocolTests,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := logrus.New()
+			log.SetOutput(ioutil.Discard)
+
+			runner := Runner{
+				agent:         &agentMock{},
+				logger:        log,
+				spec:          &spec.Definition{Scenarios: tt.scenarios, CustomTestKey: "testKey"},
+				specParentDir: "testdata/protocol",
+				scenarioTag:   "e2e-tag",
+			}
+
+			err := runner.RunProtocol()
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, 0, runner.agent.(*agentMock).SetupCalls)
+			require.Equal(t, 0, runner.agent.(*agentMock).RunCalls)
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package runtime
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/newrelic/newrelic-client-go/pkg/nrdb"
+	"github.com/newrelic/newrelic-integration-e2e-action/internal/spec"
+)
+
+const (
+	snapshotsDir     = "snapshots"
+	snapshotFileExt  = ".json"
+	maskedValue      = "<masked>"
+	snapshotFacetKey = "facet"
+)
+
+var ErrSnapshotMismatch = errors.New("results do not match the snapshot")
+
+// defaultMaskedKeys are the volatile fields of the results that are always masked in the snapshots,
+// besides the keys containing `guid` and the values equal to the scenario tag.
+var defaultMaskedKeys = []string{"timestamp", "beginTimeSeconds", "endTimeSeconds"}
+
+type snapshotRow map[string]interface{}
+
+func snapshotPath(specParentDir, name string) string {
+	return filepath.Join(specParentDir, snapshotsDir, name+snapshotFileExt)
+}
+
+// normalizeSnapshotRows masks the volatile fields of the results and sorts the rows of FACET queries
+// by facet, since their order is not deterministic.
+func normalizeSnapshotRows(results []nrdb.NRDBResult, mask []string, scenarioTag string) ([]snapshotRow, error) {
+	// A JSON round trip makes the results comparable with the ones read from the snapshot files.
+	content, err := json.Marshal(results)
+	if err != nil {
+		return nil, err
+	}
+	rows := []snapshotRow{}
+	if err := json.Unmarshal(content, &rows); err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		for key, value := range row {
+			if isMaskedKey(key, mask) || value == scenarioTag {
+				row[key] = maskedValue
+			}
+		}
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		return fmt.Sprintf("%v", rows[i][snapshotFacetKey]) < fmt.Sprintf("%v", rows[j][snapshotFacetKey])
+	})
+	return rows, nil
+}
+
+func isMaskedKey(key string, mask []string) bool {
+	if strings.Contains(strings.ToLower(key), "guid") {
+		return true
+	}
+	for _, pattern := range append(defaultMaskedKeys, mask...) {
+		if spec.MatchesPattern(pattern, key) {
+			return true
+		}
+	}
+	return false
+}
+
+func writeSnapshot(path string, rows []snapshotRow) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("creating snapshots dir: %w", err)
+	}
+
+	content, err := json.MarshalIndent(rows, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(content, '\n'), 0644)
+}
+
+func readSnapshot(path string) ([]snapshotRow, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rows := []snapshotRow{}
+	if err := json.Unmarshal(content, &rows); err != nil {
+		return nil, fmt.Errorf("parsing snapshot %s: %w", path, err)
+	}
+	return rows, nil
+}
+
+// diffSnapshot returns a line for each difference between the snapshot and the actual rows, with
+// numbers compared within the relative tolerance.
+func diffSnapshot(expected, actual []snapshotRow, tolerance float64) []string {
+	var diff []string
+	if len(expected) != len(actual) {
+		diff = append(diff, fmt.Sprintf("rows: -%d +%d", len(expected), len(actual)))
+	}
+
+	for i := 0; i < len(expected) && i < len(actual); i++ {
+		keys := map[string]bool{}
+		for key := range expected[i] {
+			keys[key] = true
+		}
+		for key := range actual[i] {
+			keys[key] = true
+		}
+		sortedKeys := make([]string, 0, len(keys))
+		for key := range keys {
+			sortedKeys = append(sortedKeys, key)
+		}
+		sort.Strings(sortedKeys)
+
+		for _, key := range sortedKeys {
+			expectedValue, inExpected := expected[i][key]
+			actualValue, inActual := actual[i][key]
+			switch {
+			case !inExpected:
+				diff = append(diff, fmt.Sprintf("row[%d].%s: + %v", i, key, actualValue))
+			case !inActual:
+				diff = append(diff, fmt.Sprintf("row[%d].%s: - %v", i, key, expectedValue))
+			case !snapshotValuesEqual(expectedValue, actualValue, tolerance):
+				diff = append(diff, fmt.Sprintf("row[%d].%s: - %v + %v", i, key, expectedValue, actualValue))
+			}
+		}
+	}
+	return diff
+}
+
+func snapshotValuesEqual(expected, actual interface{}, tolerance float64) bool {
+	expectedFloat, expectedIsFloat := expected.(float64)
+	actualFloat, actualIsFloat := actual.(float64)
+	if expectedIsFloat && actualIsFloat {
+		return math.Abs(actualFloat-expectedFloat) <= tolerance*math.Abs(expectedFloat)
+	}
+
+	expectedList, expectedIsList := expected.([]interface{})
+	actualList, actualIsList := actual.([]interface{})
+	if expectedIsList && actualIsList {
+		if len(expectedList) != len(actualList) {
+			return false
+		}
+		for i := range expectedList {
+			if !snapshotValuesEqual(expectedList[i], actualList[i], tolerance) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(expected, actual)
+}
@@ -0,0 +1,131 @@
+package runtime
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/newrelic/newrelic-client-go/pkg/nrdb"
+	"github.com/newrelic/newrelic-integration-e2e-action/internal/newrelic"
+	"github.com/newrelic/newrelic-integration-e2e-action/internal/spec"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNRQLTester_testSnapshot(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+	specParentDir := t.TempDir()
+
+	nrql := spec.TestNRQL{
+		Query:    "SELECT count(*) FROM Metric FACET topic",
+		Snapshot: &spec.TestNRQLSnapshot{Name: "topics"},
+	}
+
+	nrqlTester := NewNRQLTester(clientMock{}, log, specParentDir, false)
+	errors := nrqlTester.Test(spec.Tests{NRQLs: []spec.TestNRQL{nrql}}, "testKey", "scenario-1", newrelic.TimeRange{})
+	require.Len(t, errors, 1, "the snapshot has not been recorded yet")
+
+	// The snapshot is not recorded when the other assertions of the test fail.
+	updater := NewNRQLTester(clientMock{}, log, specParentDir, true)
+	failing := nrql
+	failing.ExpectedResults = []spec.TestNRQLExpectedResult{{Key: "count", Value: 99.0}}
+	require.Len(t, updater.Test(spec.Tests{NRQLs: []spec.TestNRQL{failing}}, "testKey", "scenario-1", newrelic.TimeRange{}), 1)
+	_, err := readSnapshot(snapshotPath(specParentDir, "topics"))
+	require.Error(t, err)
+
+	require.Empty(t, updater.Test(spec.Tests{NRQLs: []spec.TestNRQL{nrql}}, "testKey", "scenario-1", newrelic.TimeRange{}))
+
+	rows, err := readSnapshot(snapshotPath(specParentDir, "topics"))
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, maskedValue, rows[0]["timestamp"])
+	assert.Equal(t, maskedValue, rows[0]["entity.guid"])
+	assert.Equal(t, maskedValue, rows[0]["testKey"])
+	assert.Equal(t, 10.0, rows[0]["count"])
+
+	// A different scenario tag and GUIDs are masked, so the results still match.
+	assert.Empty(t, nrqlTester.Test(spec.Tests{NRQLs: []spec.TestNRQL{nrql}}, "testKey", "scenario-2", newrelic.TimeRange{}))
+
+	rows[0]["count"] = 9.0
+	require.NoError(t, writeSnapshot(snapshotPath(specParentDir, "topics"), rows))
+	errors = nrqlTester.Test(spec.Tests{NRQLs: []spec.TestNRQL{nrql}}, "testKey", "scenario-2", newrelic.TimeRange{})
+	require.Len(t, errors, 1)
+	assert.ErrorIs(t, errors[0], ErrSnapshotMismatch)
+
+	nrql.Snapshot.Tolerance = 0.2
+	assert.Empty(t, nrqlTester.Test(spec.Tests{NRQLs: []spec.TestNRQL{nrql}}, "testKey", "scenario-2", newrelic.TimeRange{}))
+}
+
+func TestNRQLTester_testSnapshot_emptyResults(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+	specParentDir := t.TempDir()
+
+	updater := NewNRQLTester(clientMock{}, log, specParentDir, true)
+	nrql := spec.TestNRQL{Query: "empty", Snapshot: &spec.TestNRQLSnapshot{Name: "empty"}}
+	err := updater.testSnapshot(nrql, nil, "scenario-1")
+	assert.ErrorIs(t, err, newrelic.ErrNoResult)
+
+	_, err = readSnapshot(snapshotPath(specParentDir, "empty"))
+	assert.Error(t, err, "empty results should not be recorded")
+}
+
+func Test_normalizeSnapshotRows(t *testing.T) {
+	results := []nrdb.NRDBResult{
+		{"facet": "payments", "count": 3, "hostname": "host-b"},
+		{"facet": "orders", "count": 10, "hostname": "host-a", "entityGuid": "MXxJTkZSQXxOQXwx"},
+	}
+
+	rows, err := normalizeSnapshotRows(results, []string{"host*"}, "scenario-1")
+	require.NoError(t, err)
+
+	expected := []snapshotRow{
+		{"facet": "orders", "count": 10.0, "hostname": maskedValue, "entityGuid": maskedValue},
+		{"facet": "payments", "count": 3.0, "hostname": maskedValue},
+	}
+	assert.Equal(t, expected, rows)
+}
+
+func Test_diffSnapshot(t *testing.T) {
+	tests := []struct {
+		name      string
+		expected  []snapshotRow
+		actual    []snapshotRow
+		tolerance float64
+		diff      []string
+	}{
+		{
+			name:     "when the rows are equal it should return no diff",
+			expected: []snapshotRow{{"facet": "orders", "count": 10.0, "uniques": []interface{}{"a", 1.0}}},
+			actual:   []snapshotRow{{"facet": "orders", "count": 10.0, "uniques": []interface{}{"a", 1.0}}},
+		},
+		{
+			name:      "when the numbers are within the tolerance it should return no diff",
+			expected:  []snapshotRow{{"count": 10.0}},
+			actual:    []snapshotRow{{"count": 10.5}},
+			tolerance: 0.1,
+		},
+		{
+			name:     "when the values differ it should return a line for each one",
+			expected: []snapshotRow{{"count": 10.0, "state": "up", "old": true}},
+			actual:   []snapshotRow{{"count": 12.0, "state": "up", "new": 1.0}},
+			diff: []string{
+				"row[0].count: - 10 + 12",
+				"row[0].new: + 1",
+				"row[0].old: - true",
+			},
+		},
+		{
+			name:     "when the number of rows differs it should return it",
+			expected: []snapshotRow{{"count": 10.0}, {"count": 3.0}},
+			actual:   []snapshotRow{{"count": 10.0}},
+			diff:     []string{"rows: -2 +1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.diff, diffSnapshot(tt.expected, tt.actual, tt.tolerance))
+		})
+	}
+}
@@ -13,18 +13,20 @@ var defaultSettingsOptions = settingOptions{
 }
 
 type settingOptions struct {
-	logLevel      logrus.Level
-	specPath      string
-	specParentDir string
-	licenseKey    string
-	agentEnabled  bool
-	accountID     int
-	apiKey        string
-	retryAttempts int
-	retrySeconds  int
-	commitSha     string
-	region        string
-	scenarioTag   string
+	logLevel        logrus.Level
+	specPath        string
+	specParentDir   string
+	licenseKey      string
+	agentEnabled    bool
+	accountID       int
+	apiKey          string
+	retryAttempts   int
+	retrySeconds    int
+	commitSha       string
+	region          string
+	scenarioTag     string
+	keepOnFailure   bool
+	updateSnapshots bool
 }
 
 type SettingOption func(*settingOptions)
@@ -96,6 +98,18 @@ func SettingsWithScenarioTag(scenarioTag string) SettingOption {
 	}
 }
 
+func SettingsWithKeepOnFailure(keepOnFailure bool) SettingOption {
+	return func(o *settingOptions) {
+		o.keepOnFailure = keepOnFailure
+	}
+}
+
+func SettingsWithUpdateSnapshots(updateSnapshots bool) SettingOption {
+	return func(o *settingOptions) {
+		o.updateSnapshots = updateSnapshots
+	}
+}
+
 type Settings interface {
 	Logger() *logrus.Logger
 	SpecDefinition() *spec.Definition
@@ -110,21 +124,25 @@ type Settings interface {
 	CommitSha() string
 	Region() string
 	ScenarioTag() string
+	KeepOnFailure() bool
+	UpdateSnapshots() bool
 }
 
 type settings struct {
-	logger         *logrus.Logger
-	specDefinition *spec.Definition
-	agentEnabled   bool
-	specParentDir  string
-	licenseKey     string
-	accountID      int
-	apiKey         string
-	retryAttempts  int
-	retrySeconds   int
-	commitSha      string
-	region         string
-	scenarioTag    string
+	logger          *logrus.Logger
+	specDefinition  *spec.Definition
+	agentEnabled    bool
+	specParentDir   string
+	licenseKey      string
+	accountID       int
+	apiKey          string
+	retryAttempts   int
+	retrySeconds    int
+	commitSha       string
+	region          string
+	scenarioTag     string
+	keepOnFailure   bool
+	updateSnapshots bool
 }
 
 func (s *settings) Logger() *logrus.Logger {
@@ -186,6 +204,14 @@ func (s *settings) ScenarioTag() string {
 	return s.scenarioTag
 }
 
+func (s *settings) KeepOnFailure() bool {
+	return s.keepOnFailure
+}
+
+func (s *settings) UpdateSnapshots() bool {
+	return s.updateSnapshots
+}
+
 // New returns a Scheduler
 func NewSettings(
 	opts ...SettingOption) (Settings, error) {
@@ -207,17 +233,19 @@ func NewSettings(
 	logger.Debug("return with settings")
 
 	return &settings{
-		logger:         logger,
-		specDefinition: s,
-		agentEnabled:   options.agentEnabled,
-		specParentDir:  options.specParentDir,
-		licenseKey:     options.licenseKey,
-		apiKey:         options.apiKey,
-		accountID:      options.accountID,
-		retryAttempts:  options.retryAttempts,
-		retrySeconds:   options.retrySeconds,
-		commitSha:      options.commitSha,
-		region:         options.region,
-		scenarioTag:    options.scenarioTag,
+		logger:          logger,
+		specDefinition:  s,
+		agentEnabled:    options.agentEnabled,
+		specParentDir:   options.specParentDir,
+		licenseKey:      options.licenseKey,
+		apiKey:          options.apiKey,
+		accountID:       options.accountID,
+		retryAttempts:   options.retryAttempts,
+		retrySeconds:    options.retrySeconds,
+		commitSha:       options.commitSha,
+		region:          options.region,
+		scenarioTag:     options.scenarioTag,
+		keepOnFailure:   options.keepOnFailure,
+		updateSnapshots: options.updateSnapshots,
 	}, nil
 }
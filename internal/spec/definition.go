@@ -3,14 +3,30 @@ package spec
 import (
 	"errors"
 	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
 
 	yaml "gopkg.in/yaml.v3"
 )
 
 var (
-	ErrInvalidConfig = errors.New("invalid NRQL test config")
+	ErrInvalidConfig              = errors.New("invalid NRQL test config")
+	ErrInvalidAgentLogsConfig     = errors.New("invalid agent logs test config")
+	ErrInvalidCompareConfig       = errors.New("invalid compare test config")
+	ErrInvalidEntitiesConfig      = errors.New("invalid entities test config")
+	ErrInvalidExportersConfig     = errors.New("invalid exporters test config")
+	ErrInvalidLogsConfig          = errors.New("invalid logs test config")
+	ErrInvalidMetricsConfig       = errors.New("invalid metrics test config")
+	ErrInvalidProtocolConfig      = errors.New("invalid protocol test config")
+	ErrInvalidRelationshipsConfig = errors.New("invalid relationships test config")
+	ErrInvalidStepConfig          = errors.New("invalid step config")
 )
 
+// MetricDataType is the table of the dimensional metrics.
+const MetricDataType = "Metric"
+
 const defaultCustomTagKey = "testKey"
 
 type Definition struct {
@@ -33,6 +49,16 @@ type Scenario struct {
 	Before       []string      `yaml:"before"`
 	After        []string      `yaml:"after"`
 	Tests        Tests         `yaml:"tests"`
+	Steps        []Step        `yaml:"steps"`
+}
+
+// Step of a multi-step scenario, executed in order after the scenario tests. Each step either runs
+// commands, waits, or asserts tests scoped to the data reported since the latest run step.
+type Step struct {
+	Description string   `yaml:"description"`
+	Run         []string `yaml:"run"`
+	Wait        string   `yaml:"wait"`
+	Tests       *Tests   `yaml:"tests"`
 }
 
 type Integration struct {
@@ -44,41 +70,292 @@ type Integration struct {
 }
 
 type Tests struct {
-	NRQLs    []TestNRQL    `yaml:"nrqls"`
-	Entities []TestEntity  `yaml:"entities"`
-	Metrics  []TestMetrics `yaml:"metrics"`
-	Scripts  []string      `yaml:"scripts"`
+	NRQLs         []TestNRQL         `yaml:"nrqls"`
+	Entities      []TestEntity       `yaml:"entities"`
+	Metrics       []TestMetrics      `yaml:"metrics"`
+	Logs          []TestLogs         `yaml:"logs"`
+	Relationships []TestRelationship `yaml:"relationships"`
+	Compare       []TestCompare      `yaml:"compare"`
+	AgentLogs     *TestAgentLogs     `yaml:"agent_logs"`
+	Protocol      []TestProtocol     `yaml:"protocol"`
+	Exporters     []TestExporter     `yaml:"exporters"`
+	Scripts       []string           `yaml:"scripts"`
 }
 
 type TestNRQL struct {
 	Query           string                   `yaml:"query"`
 	ErrorExpected   bool                     `yaml:"error_expected"`
 	ExpectedResults []TestNRQLExpectedResult `yaml:"expected_results"`
+	Timeseries      *TestNRQLTimeseries      `yaml:"timeseries"`
+	Snapshot        *TestNRQLSnapshot        `yaml:"snapshot"`
+	Assert          string                   `yaml:"assert"`
+	// Assertion is the Assert expression compiled when validating the spec file.
+	Assertion *Assertion `yaml:"-"`
+}
+
+// TestNRQLSnapshot compares the results of the query with a golden file recorded with update_snapshots.
+type TestNRQLSnapshot struct {
+	Name      string   `yaml:"name"`
+	Tolerance float64  `yaml:"tolerance"`
+	Mask      []string `yaml:"mask"`
+}
+
+// TestNRQLTimeseries asserts the buckets of a TIMESERIES query. Buckets before warmup_buckets are not checked.
+type TestNRQLTimeseries struct {
+	Key           string   `yaml:"key"`
+	WarmupBuckets int      `yaml:"warmup_buckets"`
+	NonNull       bool     `yaml:"non_null"`
+	Monotonic     bool     `yaml:"monotonic"`
+	MinRate       *float64 `yaml:"min_rate"`
+	MaxRate       *float64 `yaml:"max_rate"`
+	MaxGapBuckets *int     `yaml:"max_gap_buckets"`
 }
 
 type TestNRQLExpectedResult struct {
-	Key               string   `yaml:"key"`
-	Value             any      `yaml:"value"`
-	LowerBoundedValue *float64 `yaml:"lowerBoundedValue"`
-	UpperBoundedValue *float64 `yaml:"upperBoundedValue"`
+	Key               string         `yaml:"key"`
+	Value             any            `yaml:"value"`
+	LowerBoundedValue *float64       `yaml:"lowerBoundedValue"`
+	UpperBoundedValue *float64       `yaml:"upperBoundedValue"`
+	NotEqual          any            `yaml:"not_equal"`
+	Regex             string         `yaml:"regex"`
+	Contains          any            `yaml:"contains"`
+	OneOf             []any          `yaml:"one_of"`
+	Type              string         `yaml:"type"`
+	Approx            *Approx        `yaml:"approx"`
+	Facet             map[string]any `yaml:"facet"`
+	Rows              string         `yaml:"rows"`
+}
+
+// UnmarshalYAML decodes the expected result, taking an unquoted `type: null` as the null type instead of
+// an empty type.
+func (expectedResult *TestNRQLExpectedResult) UnmarshalYAML(value *yaml.Node) error {
+	type plainExpectedResult TestNRQLExpectedResult
+	if err := value.Decode((*plainExpectedResult)(expectedResult)); err != nil {
+		return err
+	}
+
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		key, val := value.Content[i], value.Content[i+1]
+		if key.Value == "type" && val.Kind == yaml.ScalarNode && val.ShortTag() == "!!null" {
+			expectedResult.Type = ResultTypeNull
+		}
+	}
+	return nil
+}
+
+// Modes of the `rows` option of the expected results selecting the rows by facet instead of by index.
+const (
+	RowsEvery = "every"
+	RowsAny   = "any"
+)
+
+// IsRowSelector returns true if the expected result selects the rows to assert by facet or rows mode
+// instead of by their index.
+func (expectedResult TestNRQLExpectedResult) IsRowSelector() bool {
+	return expectedResult.Facet != nil || expectedResult.Rows != ""
+}
+
+// Types of the values of the NRQL results that can be asserted with the `type` operator.
+const (
+	ResultTypeString = "string"
+	ResultTypeNumber = "number"
+	ResultTypeBool   = "bool"
+	ResultTypeNull   = "null"
+)
+
+// Approx expects a numeric value within an absolute and/or relative (i.e. 0.05 for 5%) tolerance.
+type Approx struct {
+	Value             *float64 `yaml:"value"`
+	Tolerance         float64  `yaml:"tolerance"`
+	RelativeTolerance float64  `yaml:"relative_tolerance"`
 }
 
 type TestEntity struct {
-	Type           string `yaml:"type"`
-	DataType       string `yaml:"data_type"`
-	MetricName     string `yaml:"metric_name"`
-	ExpectedNumber int    `yaml:"expected_number"`
+	Type           string            `yaml:"type"`
+	DataType       string            `yaml:"data_type"`
+	MetricName     string            `yaml:"metric_name"`
+	Attribute      string            `yaml:"attribute"`
+	ExpectedNumber int               `yaml:"expected_number"`
+	Min            int               `yaml:"min"`
+	Max            int               `yaml:"max"`
+	Exact          int               `yaml:"exact"`
+	Absent         bool              `yaml:"absent"`
+	NamePattern    string            `yaml:"name_pattern"`
+	Tags           map[string]string `yaml:"tags"`
+	Attributes     *EntityAttributes `yaml:"attributes"`
+}
+
+// EntityAttributes are checked against the entity returned by New Relic for each entity found.
+type EntityAttributes struct {
+	Domain        string   `yaml:"domain"`
+	Reporting     *bool    `yaml:"reporting"`
+	GoldenMetrics []string `yaml:"golden_metrics"`
+}
+
+// TestRelationship checks the relationships of the entities found by the entities test of the same type.
+type TestRelationship struct {
+	EntityType string `yaml:"entity_type"`
+	Type       string `yaml:"type"`
+	TargetType string `yaml:"target_type"`
+	MinCount   int    `yaml:"min_count"`
+	Direction  string `yaml:"direction"`
+}
+
+// Directions of a relationship test, relative to the entity of the test. target_type is the type of the
+// entity at the other end of the relationship in every direction.
+const (
+	DirectionOutgoing = "outgoing"
+	DirectionIncoming = "incoming"
+	DirectionAny      = "any"
+)
+
+// TestCompare runs several NRQL queries and asserts relations between their named values.
+type TestCompare struct {
+	Values    []CompareValue    `yaml:"values"`
+	Relations []CompareRelation `yaml:"relations"`
+}
+
+// CompareValue is the value of the key in the first row of the results of the query.
+type CompareValue struct {
+	Name  string `yaml:"name"`
+	Query string `yaml:"query"`
+	Key   string `yaml:"key"`
+}
+
+// CompareRelation asserts the relation between the values named left and right.
+type CompareRelation struct {
+	Left      string  `yaml:"left"`
+	Right     string  `yaml:"right"`
+	Relation  string  `yaml:"relation"`
+	Ratio     float64 `yaml:"ratio"`
+	Tolerance float64 `yaml:"tolerance"`
+}
+
+// Relations between the values of a compare test.
+const (
+	RelationEqual    = "equal"
+	RelationRatio    = "ratio"
+	RelationLessThan = "less_than"
+)
+
+type TestLogs struct {
+	Message      string            `yaml:"message"`
+	MessageRegex string            `yaml:"message_regex"`
+	Attributes   map[string]string `yaml:"attributes"`
+	MinCount     int               `yaml:"min_count"`
+}
+
+// DefaultAgentLogsFailOn are the patterns of the agent log lines always failing an agent logs test,
+// unless the lines are allowed.
+var DefaultAgentLogsFailOn = []string{
+	`integration exited with error`,
+	`panic:`,
+	`level=error`,
+}
+
+// TestAgentLogs scans the logs of the agent container of the scenario. Every pattern is a regular
+// expression matched against each log line.
+type TestAgentLogs struct {
+	// FailOn are patterns failing the test, in addition to DefaultAgentLogsFailOn.
+	FailOn []string `yaml:"fail_on"`
+	// Allow are patterns of the lines ignored by FailOn, like known errors.
+	Allow []string `yaml:"allow"`
+	// MustContain are patterns at least one line must match.
+	MustContain []string `yaml:"must_contain"`
+}
+
+// FailOnPatterns returns the default patterns failing the test along with the ones of the spec.
+func (agentLogsTest TestAgentLogs) FailOnPatterns() []string {
+	return append(append([]string{}, DefaultAgentLogsFailOn...), agentLogsTest.FailOn...)
+}
+
+const defaultProtocolTimeout = 30 * time.Second
+
+// TestProtocol executes the binary of an integration of the scenario locally, the way the agent does, and
+// validates its output against the integrations SDK protocol and the metrics source file.
+type TestProtocol struct {
+	// Integration is the name of the integration of the scenario to execute.
+	Integration string `yaml:"integration"`
+	// Source is the metrics source file the emitted metrics are checked against.
+	Source  string `yaml:"source"`
+	Timeout string `yaml:"timeout"`
+	// ScenarioIntegration is the integration named by Integration, set when parsing the spec file.
+	ScenarioIntegration *Integration `yaml:"-"`
+	Exceptions          `yaml:",inline"`
+}
+
+// TimeoutDuration returns the maximum duration of the execution of the integration. default: 30s.
+func (protocolTest TestProtocol) TimeoutDuration() (time.Duration, error) {
+	if protocolTest.Timeout == "" {
+		return defaultProtocolTimeout, nil
+	}
+	timeout, err := time.ParseDuration(protocolTest.Timeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timeout %q: %w", protocolTest.Timeout, err)
+	}
+	if timeout <= 0 {
+		return 0, fmt.Errorf("invalid timeout %q: must be positive", protocolTest.Timeout)
+	}
+	return timeout, nil
+}
+
+// TestExporter scrapes the metrics endpoint of a Prometheus exporter of the scenario, to tell the metrics
+// missing at the exporter from the ones lost later in the pipeline.
+type TestExporter struct {
+	// URL of the metrics endpoint of the exporter (i.e. `http://localhost:9121/metrics`).
+	URL string `yaml:"url"`
+	// Source is the metrics source file whose metrics must be exposed by the exporter.
+	Source string `yaml:"source"`
+	// Metrics are patterns of series that must be exposed by the exporter, in addition to the source ones.
+	Metrics    []string `yaml:"metrics"`
+	Exceptions `yaml:",inline"`
 }
 
 type TestMetrics struct {
 	Source           string `yaml:"source"`
 	ExceptionsSource string `yaml:"exceptions_source"`
-	Exceptions       `yaml:",inline"`
+	// EventTypes are the samples whose attributes are checked, instead of the dimensional metrics.
+	EventTypes      []string `yaml:"event_types"`
+	CheckDimensions bool     `yaml:"check_dimensions"`
+	CheckTypes      bool     `yaml:"check_types"`
+	Cadence         *Cadence `yaml:"cadence"`
+	// UnexpectedMetrics enables the check of reported metrics not declared in the source.
+	UnexpectedMetrics *UnexpectedMetrics `yaml:"unexpected_metrics"`
+	// StrictExceptions makes expired and stale exceptions fail the test instead of logging a warning.
+	StrictExceptions bool `yaml:"strict_exceptions"`
+	// ServiceVersion is the version of the monitored service, used to skip the metrics out of their
+	// min_version and max_version range.
+	ServiceVersion string `yaml:"service_version"`
+	// ValueRules are the sanity checks of the values of the metrics by metric name. They take precedence
+	// over the value_rules declared in the source.
+	ValueRules map[string]ValueRules `yaml:"value_rules"`
+	Exceptions `yaml:",inline"`
+}
+
+const (
+	UnexpectedMetricsWarn = "warn"
+	UnexpectedMetricsFail = "fail"
+)
+
+type UnexpectedMetrics struct {
+	// Mode is either warn, to only log the unexpected metrics, or fail.
+	Mode string `yaml:"mode"`
+	// Allow are the metrics reported that are expected not to be declared, like the ones added by the agent.
+	Allow []string `yaml:"allow"`
+}
+
+// Cadence configures the check of the reporting interval of the metrics against their defaultResolution.
+type Cadence struct {
+	// Tolerance is the fraction the resolution is widened when counting the expected data points.
+	Tolerance float64 `yaml:"tolerance"`
+	// MaxGaps is the number of missed reporting cycles allowed for each entity during the scenario.
+	MaxGaps int `yaml:"max_gaps"`
 }
 
 type Exceptions struct {
-	ExceptEntities []string `yaml:"except_entities"`
-	ExceptMetrics  []string `yaml:"except_metrics"`
+	ExceptEntities   []Exception `yaml:"except_entities"`
+	ExceptMetrics    []Exception `yaml:"except_metrics"`
+	ExceptDimensions []Exception `yaml:"except_dimensions"`
 }
 
 func ParseExceptionsFile(content []byte) (*Exceptions, error) {
@@ -88,6 +365,10 @@ func ParseExceptionsFile(content []byte) (*Exceptions, error) {
 		return nil, err
 	}
 
+	if err := exceptions.validate(); err != nil {
+		return nil, err
+	}
+
 	return exceptions, nil
 }
 
@@ -98,10 +379,20 @@ func ParseDefinitionFile(content []byte) (*Definition, error) {
 	}
 
 	for _, scenario := range specDefinition.Scenarios {
-		for _, nrql := range scenario.Tests.NRQLs {
-			err := nrql.validate()
-			if err != nil {
-				return nil, err
+		if err := scenario.Tests.validate(); err != nil {
+			return nil, err
+		}
+		if err := scenario.Tests.resolveIntegrations(scenario.Integrations); err != nil {
+			return nil, err
+		}
+		for i, step := range scenario.Steps {
+			if err := step.validate(); err != nil {
+				return nil, fmt.Errorf("%w: steps[%d]", err, i)
+			}
+			if step.Tests != nil {
+				if err := step.Tests.resolveIntegrations(scenario.Integrations); err != nil {
+					return nil, fmt.Errorf("%w: steps[%d]", err, i)
+				}
 			}
 		}
 	}
@@ -113,11 +404,154 @@ func ParseDefinitionFile(content []byte) (*Definition, error) {
 	return specDefinition, nil
 }
 
-func (nrqlTest TestNRQL) validate() error {
+func (tests Tests) validate() error {
+	// The NRQL tests are validated in place to keep their compiled assertions.
+	for i := range tests.NRQLs {
+		err := tests.NRQLs[i].validate()
+		if err != nil {
+			return err
+		}
+	}
+	for i, entity := range tests.Entities {
+		if err := entity.validate(); err != nil {
+			return fmt.Errorf("%w: entities[%d]", err, i)
+		}
+	}
+	for i, compare := range tests.Compare {
+		if err := compare.validate(); err != nil {
+			return fmt.Errorf("%w: compare[%d]", err, i)
+		}
+	}
+	for i, relationship := range tests.Relationships {
+		if err := relationship.validate(tests.Entities); err != nil {
+			return fmt.Errorf("%w: relationships[%d]", err, i)
+		}
+	}
+	for i, metrics := range tests.Metrics {
+		if err := metrics.validate(); err != nil {
+			return fmt.Errorf("%w: metrics[%d]", err, i)
+		}
+	}
+	for i, logs := range tests.Logs {
+		if err := logs.validate(); err != nil {
+			return fmt.Errorf("%w: logs[%d]", err, i)
+		}
+	}
+	if tests.AgentLogs != nil {
+		if err := tests.AgentLogs.validate(); err != nil {
+			return err
+		}
+	}
+	for i, exporter := range tests.Exporters {
+		if err := exporter.validate(); err != nil {
+			return fmt.Errorf("%w: exporters[%d]", err, i)
+		}
+	}
+	for i, protocol := range tests.Protocol {
+		if err := protocol.validate(); err != nil {
+			return fmt.Errorf("%w: protocol[%d]", err, i)
+		}
+	}
+	return nil
+}
+
+// resolveIntegrations sets the integrations of the scenario executed by the protocol tests.
+func (tests Tests) resolveIntegrations(integrations []Integration) error {
+	for i := range tests.Protocol {
+		integration := FindIntegration(integrations, tests.Protocol[i].Integration)
+		if integration == nil {
+			return fmt.Errorf("%w: protocol[%d]: integration %q is not an integration of the scenario", ErrInvalidProtocolConfig, i, tests.Protocol[i].Integration)
+		}
+		if integration.BinaryPath == "" {
+			return fmt.Errorf("%w: protocol[%d]: integration %q has no binary_path", ErrInvalidProtocolConfig, i, tests.Protocol[i].Integration)
+		}
+		tests.Protocol[i].ScenarioIntegration = integration
+	}
+	return nil
+}
+
+// FindIntegration returns the integration with the given name, nil if there is none.
+func FindIntegration(integrations []Integration, name string) *Integration {
+	for i := range integrations {
+		if integrations[i].Name == name {
+			return &integrations[i]
+		}
+	}
+	return nil
+}
+
+func (step Step) validate() error {
+	actions := 0
+	for _, set := range []bool{len(step.Run) > 0, step.Wait != "", step.Tests != nil} {
+		if set {
+			actions++
+		}
+	}
+	if actions != 1 {
+		return fmt.Errorf("%w: a step requires exactly one of run, wait or tests", ErrInvalidStepConfig)
+	}
+
+	if step.Wait != "" {
+		if _, err := step.WaitDuration(); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidStepConfig, err)
+		}
+	}
+
+	if step.Tests != nil {
+		return step.Tests.validate()
+	}
+	return nil
+}
+
+// WaitDuration returns the duration of a wait step, like `30s` or `2m`.
+func (step Step) WaitDuration() (time.Duration, error) {
+	wait, err := time.ParseDuration(step.Wait)
+	if err != nil {
+		return 0, fmt.Errorf("invalid wait %q: %w", step.Wait, err)
+	}
+	if wait <= 0 {
+		return 0, fmt.Errorf("invalid wait %q: must be positive", step.Wait)
+	}
+	return wait, nil
+}
+
+func (nrqlTest *TestNRQL) validate() error {
 	if nrqlTest.Query == "" {
 		return fmt.Errorf("%w: missing query param", ErrInvalidConfig)
 	}
 
+	if nrqlTest.Timeseries != nil {
+		if nrqlTest.ErrorExpected || nrqlTest.ExpectedResults != nil {
+			return fmt.Errorf("%w: timeseries cannot be used with error_expected or expected_results", ErrInvalidConfig)
+		}
+		if !strings.Contains(strings.ToUpper(nrqlTest.Query), "TIMESERIES") {
+			return fmt.Errorf("%w: timeseries requires a TIMESERIES query", ErrInvalidConfig)
+		}
+		if err := nrqlTest.Timeseries.validate(); err != nil {
+			return err
+		}
+	}
+
+	if nrqlTest.Assert != "" {
+		if nrqlTest.ErrorExpected || nrqlTest.ExpectedResults != nil || nrqlTest.Timeseries != nil {
+			return fmt.Errorf("%w: assert cannot be used with error_expected, expected_results or timeseries", ErrInvalidConfig)
+		}
+		assertion, err := CompileAssertion(nrqlTest.Assert)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidConfig, err)
+		}
+		nrqlTest.Assertion = assertion
+	}
+
+	if nrqlTest.Snapshot != nil {
+		if nrqlTest.ErrorExpected {
+			return fmt.Errorf("%w: snapshot cannot be used with error_expected", ErrInvalidConfig)
+		}
+		if err := nrqlTest.Snapshot.validate(); err != nil {
+			return err
+		}
+	}
+
 	if nrqlTest.ExpectedResults != nil {
 		// Check expected value config
 		if nrqlTest.ErrorExpected {
@@ -135,15 +569,353 @@ func (nrqlTest TestNRQL) validate() error {
 	return nil
 }
 
+func (snapshot TestNRQLSnapshot) validate() error {
+	if snapshot.Name == "" || strings.ContainsAny(snapshot.Name, `/\`) {
+		return fmt.Errorf("%w: snapshot name is required and cannot contain path separators", ErrInvalidConfig)
+	}
+	if snapshot.Tolerance < 0 {
+		return fmt.Errorf("%w: snapshot tolerance cannot be negative", ErrInvalidConfig)
+	}
+	for _, pattern := range snapshot.Mask {
+		if err := ValidatePattern(pattern); err != nil {
+			return fmt.Errorf("%w: snapshot mask: %s", ErrInvalidConfig, err)
+		}
+	}
+	return nil
+}
+
+func (timeseries TestNRQLTimeseries) validate() error {
+	if timeseries.Key == "" {
+		return fmt.Errorf("%w: timeseries key is required", ErrInvalidConfig)
+	}
+	if timeseries.WarmupBuckets < 0 || (timeseries.MaxGapBuckets != nil && *timeseries.MaxGapBuckets < 0) {
+		return fmt.Errorf("%w: timeseries warmup_buckets and max_gap_buckets cannot be negative", ErrInvalidConfig)
+	}
+	if timeseries.MinRate != nil && timeseries.MaxRate != nil && *timeseries.MinRate > *timeseries.MaxRate {
+		return fmt.Errorf("%w: timeseries min_rate cannot be greater than max_rate", ErrInvalidConfig)
+	}
+	if !timeseries.NonNull && !timeseries.Monotonic && timeseries.MinRate == nil && timeseries.MaxRate == nil && timeseries.MaxGapBuckets == nil {
+		return fmt.Errorf("%w: timeseries requires non_null, monotonic, min_rate, max_rate or max_gap_buckets", ErrInvalidConfig)
+	}
+	return nil
+}
+
 func (expectedResult TestNRQLExpectedResult) validate() error {
-	if expectedResult.Value != nil {
-		// Ensure bounds are nil
-		if expectedResult.LowerBoundedValue != nil || expectedResult.UpperBoundedValue != nil {
-			return fmt.Errorf("%w: expected value cannot be used with bounded expected values", ErrInvalidConfig)
-		}
-	} else {
-		if expectedResult.LowerBoundedValue == nil && expectedResult.UpperBoundedValue == nil {
-			return fmt.Errorf("%w: at least 1 expected value bound is required when not using expected value", ErrInvalidConfig)
+	operators := 0
+	for _, set := range []bool{
+		expectedResult.Value != nil,
+		expectedResult.LowerBoundedValue != nil || expectedResult.UpperBoundedValue != nil,
+		expectedResult.NotEqual != nil,
+		expectedResult.Regex != "",
+		expectedResult.Contains != nil,
+		expectedResult.OneOf != nil,
+		expectedResult.Type != "",
+		expectedResult.Approx != nil,
+	} {
+		if set {
+			operators++
+		}
+	}
+
+	if operators == 0 {
+		return fmt.Errorf("%w: one of value, bounds, not_equal, regex, contains, one_of, type or approx is required", ErrInvalidConfig)
+	}
+	if operators > 1 {
+		return fmt.Errorf("%w: only one of value, bounds, not_equal, regex, contains, one_of, type or approx can be used", ErrInvalidConfig)
+	}
+
+	if expectedResult.Rows != "" && expectedResult.Rows != RowsEvery && expectedResult.Rows != RowsAny {
+		return fmt.Errorf("%w: rows must be %q or %q, got %q", ErrInvalidConfig, RowsEvery, RowsAny, expectedResult.Rows)
+	}
+
+	if expectedResult.Facet != nil && len(expectedResult.Facet) == 0 {
+		return fmt.Errorf("%w: facet cannot be empty", ErrInvalidConfig)
+	}
+
+	if expectedResult.Regex != "" {
+		if _, err := regexp.Compile(expectedResult.Regex); err != nil {
+			return fmt.Errorf("%w: invalid regex: %s", ErrInvalidConfig, err)
+		}
+	}
+
+	if expectedResult.OneOf != nil && len(expectedResult.OneOf) == 0 {
+		return fmt.Errorf("%w: one_of cannot be empty", ErrInvalidConfig)
+	}
+
+	switch expectedResult.Type {
+	case "", ResultTypeString, ResultTypeNumber, ResultTypeBool, ResultTypeNull:
+	default:
+		return fmt.Errorf("%w: type must be one of %s, %s, %s or %s, got %q", ErrInvalidConfig, ResultTypeString, ResultTypeNumber, ResultTypeBool, ResultTypeNull, expectedResult.Type)
+	}
+
+	if approx := expectedResult.Approx; approx != nil {
+		if approx.Value == nil {
+			return fmt.Errorf("%w: approx value is required", ErrInvalidConfig)
+		}
+		if approx.Tolerance < 0 || approx.RelativeTolerance < 0 {
+			return fmt.Errorf("%w: approx tolerances cannot be negative", ErrInvalidConfig)
+		}
+		if approx.Tolerance == 0 && approx.RelativeTolerance == 0 {
+			return fmt.Errorf("%w: approx requires tolerance or relative_tolerance", ErrInvalidConfig)
+		}
+	}
+	return nil
+}
+
+// CountBounds returns the minimum and maximum number of entities expected by the test. A maximum of 0
+// means there is no upper bound, except for absent entities.
+func (entityTest TestEntity) CountBounds() (int, int) {
+	switch {
+	case entityTest.Absent:
+		return 0, 0
+	case entityTest.Exact > 0:
+		return entityTest.Exact, entityTest.Exact
+	case entityTest.Min > 0:
+		return entityTest.Min, entityTest.Max
+	case entityTest.ExpectedNumber > 0:
+		return entityTest.ExpectedNumber, entityTest.Max
+	}
+	// By default if not notified, we expect at least one entity
+	return 1, entityTest.Max
+}
+
+func (entityTest TestEntity) validate() error {
+	if entityTest.Attribute != "" {
+		if entityTest.DataType == MetricDataType {
+			return fmt.Errorf("%w: attribute cannot be used with data_type %s, use metric_name", ErrInvalidEntitiesConfig, MetricDataType)
+		}
+		if entityTest.MetricName != "" {
+			return fmt.Errorf("%w: attribute cannot be used with metric_name", ErrInvalidEntitiesConfig)
+		}
+	}
+
+	if entityTest.ExpectedNumber < 0 || entityTest.Min < 0 || entityTest.Max < 0 || entityTest.Exact < 0 {
+		return fmt.Errorf("%w: entity counts cannot be negative", ErrInvalidEntitiesConfig)
+	}
+
+	if entityTest.ExpectedNumber > 0 && entityTest.Min > 0 {
+		return fmt.Errorf("%w: expected_number cannot be used with min", ErrInvalidEntitiesConfig)
+	}
+
+	if entityTest.Exact > 0 && (entityTest.ExpectedNumber > 0 || entityTest.Min > 0 || entityTest.Max > 0) {
+		return fmt.Errorf("%w: exact cannot be used with expected_number, min or max", ErrInvalidEntitiesConfig)
+	}
+
+	if minCount, maxCount := entityTest.CountBounds(); maxCount > 0 && minCount > maxCount {
+		return fmt.Errorf("%w: min cannot be greater than max", ErrInvalidEntitiesConfig)
+	}
+
+	if entityTest.Absent {
+		if entityTest.ExpectedNumber > 0 || entityTest.Min > 0 || entityTest.Max > 0 || entityTest.Exact > 0 {
+			return fmt.Errorf("%w: absent cannot be used with expected_number, min, max or exact", ErrInvalidEntitiesConfig)
+		}
+		if entityTest.NamePattern != "" || len(entityTest.Tags) > 0 || entityTest.Attributes != nil {
+			return fmt.Errorf("%w: absent cannot be used with name_pattern, tags or attributes", ErrInvalidEntitiesConfig)
+		}
+	}
+
+	if entityTest.NamePattern != "" {
+		if err := ValidatePattern(entityTest.NamePattern); err != nil {
+			return fmt.Errorf("%w: name_pattern: %s", ErrInvalidEntitiesConfig, err)
+		}
+	}
+
+	for key, pattern := range entityTest.Tags {
+		if err := ValidatePattern(pattern); err != nil {
+			return fmt.Errorf("%w: tag %s: %s", ErrInvalidEntitiesConfig, key, err)
+		}
+	}
+	return nil
+}
+
+func (compareTest TestCompare) validate() error {
+	if len(compareTest.Values) < 2 {
+		return fmt.Errorf("%w: at least 2 values are required", ErrInvalidCompareConfig)
+	}
+
+	names := map[string]bool{}
+	for _, value := range compareTest.Values {
+		if value.Name == "" || value.Query == "" || value.Key == "" {
+			return fmt.Errorf("%w: values require name, query and key", ErrInvalidCompareConfig)
+		}
+		if names[value.Name] {
+			return fmt.Errorf("%w: duplicated value name %s", ErrInvalidCompareConfig, value.Name)
+		}
+		names[value.Name] = true
+	}
+
+	if len(compareTest.Relations) == 0 {
+		return fmt.Errorf("%w: at least 1 relation is required", ErrInvalidCompareConfig)
+	}
+	for _, relation := range compareTest.Relations {
+		if !names[relation.Left] || !names[relation.Right] {
+			return fmt.Errorf("%w: relation between unknown values %s and %s", ErrInvalidCompareConfig, relation.Left, relation.Right)
+		}
+		if relation.Tolerance < 0 {
+			return fmt.Errorf("%w: tolerance cannot be negative", ErrInvalidCompareConfig)
+		}
+		switch relation.Relation {
+		case RelationEqual, RelationLessThan:
+		case RelationRatio:
+			if relation.Ratio <= 0 {
+				return fmt.Errorf("%w: ratio relation requires a positive ratio", ErrInvalidCompareConfig)
+			}
+		default:
+			return fmt.Errorf("%w: relation must be %q, %q or %q, got %q", ErrInvalidCompareConfig, RelationEqual, RelationRatio, RelationLessThan, relation.Relation)
+		}
+	}
+	return nil
+}
+
+func (relationshipTest TestRelationship) validate(entities []TestEntity) error {
+	if relationshipTest.EntityType == "" || relationshipTest.TargetType == "" {
+		return fmt.Errorf("%w: entity_type and target_type are required", ErrInvalidRelationshipsConfig)
+	}
+
+	if relationshipTest.MinCount < 0 {
+		return fmt.Errorf("%w: min_count cannot be negative", ErrInvalidRelationshipsConfig)
+	}
+
+	switch relationshipTest.Direction {
+	case "", DirectionOutgoing, DirectionIncoming, DirectionAny:
+	default:
+		return fmt.Errorf("%w: direction must be %q, %q or %q, got %q", ErrInvalidRelationshipsConfig, DirectionOutgoing, DirectionIncoming, DirectionAny, relationshipTest.Direction)
+	}
+
+	entity, ok := FindTestEntity(entities, relationshipTest.EntityType)
+	if !ok {
+		return fmt.Errorf("%w: no entities test of type %s in the scenario", ErrInvalidRelationshipsConfig, relationshipTest.EntityType)
+	}
+	if entity.Absent {
+		return fmt.Errorf("%w: entities of type %s are expected to be absent", ErrInvalidRelationshipsConfig, relationshipTest.EntityType)
+	}
+	return nil
+}
+
+// FindTestEntity returns the entities test looking for the given entity type.
+func FindTestEntity(entities []TestEntity, entityType string) (TestEntity, bool) {
+	for _, entity := range entities {
+		if entity.Type == entityType {
+			return entity, true
+		}
+	}
+	return TestEntity{}, false
+}
+
+func (exporterTest TestExporter) validate() error {
+	if exporterTest.URL == "" {
+		return fmt.Errorf("%w: missing url", ErrInvalidExportersConfig)
+	}
+
+	exporterURL, err := url.Parse(exporterTest.URL)
+	if err != nil {
+		return fmt.Errorf("%w: invalid url: %s", ErrInvalidExportersConfig, err)
+	}
+	if exporterURL.Scheme != "http" && exporterURL.Scheme != "https" {
+		return fmt.Errorf("%w: url %q must be http or https", ErrInvalidExportersConfig, exporterTest.URL)
+	}
+
+	if exporterTest.Source == "" && len(exporterTest.Metrics) == 0 {
+		return fmt.Errorf("%w: a source or metrics are required", ErrInvalidExportersConfig)
+	}
+
+	for _, pattern := range exporterTest.Metrics {
+		if err := ValidatePattern(pattern); err != nil {
+			return fmt.Errorf("%w: metrics: %s", ErrInvalidExportersConfig, err)
+		}
+	}
+
+	if err := exporterTest.Exceptions.validate(); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidExportersConfig, err)
+	}
+	return nil
+}
+
+func (protocolTest TestProtocol) validate() error {
+	if protocolTest.Integration == "" {
+		return fmt.Errorf("%w: missing integration", ErrInvalidProtocolConfig)
+	}
+
+	if _, err := protocolTest.TimeoutDuration(); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidProtocolConfig, err)
+	}
+
+	if err := protocolTest.Exceptions.validate(); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidProtocolConfig, err)
+	}
+	return nil
+}
+
+func (metricsTest TestMetrics) validate() error {
+	if err := metricsTest.Exceptions.validate(); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidMetricsConfig, err)
+	}
+
+	for metricName, rules := range metricsTest.ValueRules {
+		if err := rules.Validate(); err != nil {
+			return fmt.Errorf("%w: value_rules of %s: %s", ErrInvalidMetricsConfig, metricName, err)
+		}
+	}
+
+	if metricsTest.ServiceVersion != "" {
+		if err := ValidateVersion(metricsTest.ServiceVersion); err != nil {
+			return fmt.Errorf("%w: service_version: %s", ErrInvalidMetricsConfig, err)
+		}
+	}
+
+	if metricsTest.UnexpectedMetrics != nil {
+		mode := metricsTest.UnexpectedMetrics.Mode
+		if mode != UnexpectedMetricsWarn && mode != UnexpectedMetricsFail {
+			return fmt.Errorf("%w: unexpected_metrics mode must be %q or %q, got %q", ErrInvalidMetricsConfig, UnexpectedMetricsWarn, UnexpectedMetricsFail, mode)
+		}
+		for _, pattern := range metricsTest.UnexpectedMetrics.Allow {
+			if err := ValidatePattern(pattern); err != nil {
+				return fmt.Errorf("%w: unexpected_metrics allow: %s", ErrInvalidMetricsConfig, err)
+			}
+		}
+	}
+	if metricsTest.Cadence != nil {
+		if metricsTest.Cadence.Tolerance < 0 {
+			return fmt.Errorf("%w: cadence tolerance cannot be negative", ErrInvalidMetricsConfig)
+		}
+		if metricsTest.Cadence.MaxGaps < 0 {
+			return fmt.Errorf("%w: cadence max_gaps cannot be negative", ErrInvalidMetricsConfig)
+		}
+	}
+	return nil
+}
+
+func (logsTest TestLogs) validate() error {
+	if logsTest.Message != "" && logsTest.MessageRegex != "" {
+		return fmt.Errorf("%w: message cannot be used with message_regex", ErrInvalidLogsConfig)
+	}
+
+	if logsTest.MessageRegex != "" {
+		if _, err := regexp.Compile(logsTest.MessageRegex); err != nil {
+			return fmt.Errorf("%w: invalid message_regex: %s", ErrInvalidLogsConfig, err)
+		}
+	}
+
+	if logsTest.MinCount < 0 {
+		return fmt.Errorf("%w: min_count cannot be negative", ErrInvalidLogsConfig)
+	}
+	return nil
+}
+
+func (agentLogsTest TestAgentLogs) validate() error {
+	if err := validateRegexes("fail_on", agentLogsTest.FailOn); err != nil {
+		return err
+	}
+	if err := validateRegexes("allow", agentLogsTest.Allow); err != nil {
+		return err
+	}
+	return validateRegexes("must_contain", agentLogsTest.MustContain)
+}
+
+func validateRegexes(field string, patterns []string) error {
+	for i, pattern := range patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("%w: invalid %s[%d]: %s", ErrInvalidAgentLogsConfig, field, i, err)
 		}
 	}
 	return nil
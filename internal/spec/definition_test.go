@@ -1,9 +1,11 @@
 package spec
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func Test_ParseExceptionsFile(t *testing.T) {
@@ -13,18 +15,30 @@ except_metrics:
 - metric_b
 except_entities:
 - entity_a
+except_dimensions:
+- dimension_a
 `
 	exceptions, err := ParseExceptionsFile([]byte(sample))
 	assert.Nil(t, err)
 	assert.Equal(
 		t,
 		&Exceptions{
-			ExceptEntities: []string{"entity_a"},
-			ExceptMetrics:  []string{"metric_a", "metric_b"},
+			ExceptEntities:   []Exception{{Name: "entity_a"}},
+			ExceptMetrics:    []Exception{{Name: "metric_a"}, {Name: "metric_b"}},
+			ExceptDimensions: []Exception{{Name: "dimension_a"}},
 		},
 		exceptions)
 }
 
+func Test_ParseExceptionsFileInvalidPattern(t *testing.T) {
+	sample := `
+except_metrics:
+- regex:metric_(a
+`
+	_, err := ParseExceptionsFile([]byte(sample))
+	assert.Error(t, err)
+}
+
 func Test_ParseDefinitionFile(t *testing.T) {
 	sample := `
 description: |
@@ -103,7 +117,7 @@ scenarios:
 					{
 						Source: "powerdns.yml",
 						Exceptions: Exceptions{
-							ExceptMetrics: []string{"powerdns_authoritative_answers_bytes_total"},
+							ExceptMetrics: []Exception{{Name: "powerdns_authoritative_answers_bytes_total"}},
 						},
 					},
 				},
@@ -208,3 +222,798 @@ func TestTestNRQL_validate(t *testing.T) {
 		})
 	}
 }
+
+func TestTestNRQL_validateTimeseries(t *testing.T) {
+	minRate := 2.0
+	maxRate := 1.0
+	negativeGap := -1
+
+	tests := []struct {
+		name     string
+		nrqlTest TestNRQL
+		wantErr  bool
+	}{
+		{
+			name:     "a timeseries test of a TIMESERIES query does not return an error",
+			nrqlTest: TestNRQL{Query: "SELECT count(*) as 'requests' FROM Metric TIMESERIES 1 minute", Timeseries: &TestNRQLTimeseries{Key: "requests", NonNull: true, WarmupBuckets: 2}},
+			wantErr:  false,
+		},
+		{
+			name:     "a timeseries test of a query without TIMESERIES returns an error",
+			nrqlTest: TestNRQL{Query: "SELECT count(*) as 'requests' FROM Metric", Timeseries: &TestNRQLTimeseries{Key: "requests", NonNull: true}},
+			wantErr:  true,
+		},
+		{
+			name:     "a timeseries test with expected_results returns an error",
+			nrqlTest: TestNRQL{Query: "SELECT count(*) FROM Metric TIMESERIES", ExpectedResults: []TestNRQLExpectedResult{{Key: "count", Value: 1}}, Timeseries: &TestNRQLTimeseries{Key: "count", Monotonic: true}},
+			wantErr:  true,
+		},
+		{
+			name:     "a timeseries test without key returns an error",
+			nrqlTest: TestNRQL{Query: "SELECT count(*) FROM Metric TIMESERIES", Timeseries: &TestNRQLTimeseries{Monotonic: true}},
+			wantErr:  true,
+		},
+		{
+			name:     "a timeseries test without assertions returns an error",
+			nrqlTest: TestNRQL{Query: "SELECT count(*) FROM Metric TIMESERIES", Timeseries: &TestNRQLTimeseries{Key: "count", WarmupBuckets: 1}},
+			wantErr:  true,
+		},
+		{
+			name:     "a timeseries test with min_rate greater than max_rate returns an error",
+			nrqlTest: TestNRQL{Query: "SELECT count(*) FROM Metric TIMESERIES", Timeseries: &TestNRQLTimeseries{Key: "count", MinRate: &minRate, MaxRate: &maxRate}},
+			wantErr:  true,
+		},
+		{
+			name:     "a timeseries test with negative max_gap_buckets returns an error",
+			nrqlTest: TestNRQL{Query: "SELECT count(*) FROM Metric TIMESERIES", Timeseries: &TestNRQLTimeseries{Key: "count", MaxGapBuckets: &negativeGap}},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.nrqlTest.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTestNRQL_validateAssert(t *testing.T) {
+	tests := []struct {
+		name     string
+		nrqlTest TestNRQL
+		wantErr  bool
+	}{
+		{
+			name:     "a test with a valid assert does not return an error",
+			nrqlTest: TestNRQL{Query: "SELECT latest(podsTotal) as 'Pods Total' FROM Metric", Assert: `results[0]["Pods Total"] >= 1 && results.size() == 1`},
+			wantErr:  false,
+		},
+		{
+			name:     "a test with an assert that does not compile returns an error",
+			nrqlTest: TestNRQL{Query: "SELECT latest(podsTotal) as 'Pods Total' FROM Metric", Assert: `results[0]["Pods Total"] >=`},
+			wantErr:  true,
+		},
+		{
+			name:     "a test with assert and expected_results returns an error",
+			nrqlTest: TestNRQL{Query: "SELECT count(*) FROM Metric", Assert: `results.size() == 1`, ExpectedResults: []TestNRQLExpectedResult{{Key: "count", Value: 1}}},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.nrqlTest.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && tt.nrqlTest.Assertion == nil {
+				t.Errorf("validate() did not keep the compiled assertion")
+			}
+		})
+	}
+}
+
+func TestTests_validateKeepsAssertions(t *testing.T) {
+	tests := Tests{NRQLs: []TestNRQL{{Query: "SELECT count(*) FROM Metric", Assert: `results.size() == 1`}}}
+	if err := tests.validate(); err != nil {
+		t.Fatalf("validate() error = %v", err)
+	}
+	if tests.NRQLs[0].Assertion == nil || tests.NRQLs[0].Assertion.String() != `results.size() == 1` {
+		t.Errorf("validate() did not keep the compiled assertion of the test")
+	}
+}
+
+func TestTestNRQL_validateSnapshot(t *testing.T) {
+	tests := []struct {
+		name     string
+		nrqlTest TestNRQL
+		wantErr  bool
+	}{
+		{
+			name:     "a snapshot test with tolerance and mask does not return an error",
+			nrqlTest: TestNRQL{Query: "SELECT count(*) FROM Metric FACET topic", Snapshot: &TestNRQLSnapshot{Name: "topics", Tolerance: 0.1, Mask: []string{"host*"}}},
+			wantErr:  false,
+		},
+		{
+			name:     "a snapshot test without name returns an error",
+			nrqlTest: TestNRQL{Query: "SELECT count(*) FROM Metric", Snapshot: &TestNRQLSnapshot{}},
+			wantErr:  true,
+		},
+		{
+			name:     "a snapshot test with a path as name returns an error",
+			nrqlTest: TestNRQL{Query: "SELECT count(*) FROM Metric", Snapshot: &TestNRQLSnapshot{Name: "../topics"}},
+			wantErr:  true,
+		},
+		{
+			name:     "a snapshot test with negative tolerance returns an error",
+			nrqlTest: TestNRQL{Query: "SELECT count(*) FROM Metric", Snapshot: &TestNRQLSnapshot{Name: "topics", Tolerance: -1}},
+			wantErr:  true,
+		},
+		{
+			name:     "a snapshot test with an invalid mask returns an error",
+			nrqlTest: TestNRQL{Query: "SELECT count(*) FROM Metric", Snapshot: &TestNRQLSnapshot{Name: "topics", Mask: []string{"regex:(host"}}},
+			wantErr:  true,
+		},
+		{
+			name:     "a snapshot test with error_expected returns an error",
+			nrqlTest: TestNRQL{Query: "SELECT count(*) FROM Metric", ErrorExpected: true, Snapshot: &TestNRQLSnapshot{Name: "topics"}},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.nrqlTest.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTestNRQLExpectedResult_validate(t *testing.T) {
+	approxValue := 100.0
+	lowerResult := 5.0
+
+	tests := []struct {
+		name           string
+		expectedResult TestNRQLExpectedResult
+		wantErr        bool
+	}{
+		{
+			name:           "a not_equal operator does not return an error",
+			expectedResult: TestNRQLExpectedResult{Key: "status", NotEqual: "down"},
+			wantErr:        false,
+		},
+		{
+			name:           "a valid regex operator does not return an error",
+			expectedResult: TestNRQLExpectedResult{Key: "version", Regex: `\d+\.\d+\.\d+`},
+			wantErr:        false,
+		},
+		{
+			name:           "an invalid regex operator returns an error",
+			expectedResult: TestNRQLExpectedResult{Key: "version", Regex: `(\d+`},
+			wantErr:        true,
+		},
+		{
+			name:           "a contains operator does not return an error",
+			expectedResult: TestNRQLExpectedResult{Key: "uniques.state", Contains: "running"},
+			wantErr:        false,
+		},
+		{
+			name:           "a one_of operator does not return an error",
+			expectedResult: TestNRQLExpectedResult{Key: "state", OneOf: []any{"running", "starting"}},
+			wantErr:        false,
+		},
+		{
+			name:           "an empty one_of operator returns an error",
+			expectedResult: TestNRQLExpectedResult{Key: "state", OneOf: []any{}},
+			wantErr:        true,
+		},
+		{
+			name:           "a type operator with a known type does not return an error",
+			expectedResult: TestNRQLExpectedResult{Key: "state", Type: ResultTypeNull},
+			wantErr:        false,
+		},
+		{
+			name:           "a type operator with an unknown type returns an error",
+			expectedResult: TestNRQLExpectedResult{Key: "state", Type: "list"},
+			wantErr:        true,
+		},
+		{
+			name:           "an approx operator with a tolerance does not return an error",
+			expectedResult: TestNRQLExpectedResult{Key: "count", Approx: &Approx{Value: &approxValue, RelativeTolerance: 0.05}},
+			wantErr:        false,
+		},
+		{
+			name:           "an approx operator without value returns an error",
+			expectedResult: TestNRQLExpectedResult{Key: "count", Approx: &Approx{Tolerance: 1}},
+			wantErr:        true,
+		},
+		{
+			name:           "an approx operator without tolerances returns an error",
+			expectedResult: TestNRQLExpectedResult{Key: "count", Approx: &Approx{Value: &approxValue}},
+			wantErr:        true,
+		},
+		{
+			name:           "an approx operator with a negative tolerance returns an error",
+			expectedResult: TestNRQLExpectedResult{Key: "count", Approx: &Approx{Value: &approxValue, Tolerance: -1}},
+			wantErr:        true,
+		},
+		{
+			name:           "a facet with a rows mode does not return an error",
+			expectedResult: TestNRQLExpectedResult{Key: "count", Value: 10, Facet: map[string]any{"topic": "orders"}, Rows: RowsAny},
+			wantErr:        false,
+		},
+		{
+			name:           "an unknown rows mode returns an error",
+			expectedResult: TestNRQLExpectedResult{Key: "count", Value: 10, Rows: "some"},
+			wantErr:        true,
+		},
+		{
+			name:           "an empty facet returns an error",
+			expectedResult: TestNRQLExpectedResult{Key: "count", Value: 10, Facet: map[string]any{}},
+			wantErr:        true,
+		},
+		{
+			name:           "several operators return an error",
+			expectedResult: TestNRQLExpectedResult{Key: "count", Type: ResultTypeNumber, LowerBoundedValue: &lowerResult},
+			wantErr:        true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.expectedResult.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTestLogs_validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		logsTest TestLogs
+		wantErr  bool
+	}{
+		{
+			name:     "a test with message and attributes does not return an error",
+			logsTest: TestLogs{Message: "error", Attributes: map[string]string{"logtype": "nginx"}, MinCount: 2},
+			wantErr:  false,
+		},
+		{
+			name:     "a test with a valid message_regex does not return an error",
+			logsTest: TestLogs{MessageRegex: "level=(error|fatal)"},
+			wantErr:  false,
+		},
+		{
+			name:     "a test with message and message_regex returns an error",
+			logsTest: TestLogs{Message: "error", MessageRegex: "error"},
+			wantErr:  true,
+		},
+		{
+			name:     "a test with an invalid message_regex returns an error",
+			logsTest: TestLogs{MessageRegex: "level=(error"},
+			wantErr:  true,
+		},
+		{
+			name:     "a test with a negative min_count returns an error",
+			logsTest: TestLogs{MinCount: -1},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.logsTest.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTestEntity_validate(t *testing.T) {
+	tests := []struct {
+		name       string
+		entityTest TestEntity
+		wantErr    bool
+	}{
+		{
+			name:       "a test without attribute assertions does not return an error",
+			entityTest: TestEntity{Type: "POWERDNS_AUTHORITATIVE", DataType: "Metric", MetricName: "powerdns_authoritative_up"},
+			wantErr:    false,
+		},
+		{
+			name:       "a test with valid name_pattern and tags does not return an error",
+			entityTest: TestEntity{NamePattern: "*:8081", Tags: map[string]string{"clusterName": "regex:e2e-.+"}},
+			wantErr:    false,
+		},
+		{
+			name:       "a test of an event type with attribute does not return an error",
+			entityTest: TestEntity{Type: "KAFKABROKER", DataType: "KafkaBrokerSample", Attribute: "broker.IOInPerSecond"},
+			wantErr:    false,
+		},
+		{
+			name:       "a test of Metric with attribute returns an error",
+			entityTest: TestEntity{Type: "KAFKABROKER", DataType: "Metric", Attribute: "broker.IOInPerSecond"},
+			wantErr:    true,
+		},
+		{
+			name:       "a test with attribute and metric_name returns an error",
+			entityTest: TestEntity{Type: "KAFKABROKER", DataType: "KafkaBrokerSample", Attribute: "broker.IOInPerSecond", MetricName: "kafka_broker_up"},
+			wantErr:    true,
+		},
+		{
+			name:       "a test with min and max does not return an error",
+			entityTest: TestEntity{Type: "POWERDNS_AUTHORITATIVE", Min: 1, Max: 2},
+			wantErr:    false,
+		},
+		{
+			name:       "a test with min greater than max returns an error",
+			entityTest: TestEntity{Type: "POWERDNS_AUTHORITATIVE", Min: 3, Max: 2},
+			wantErr:    true,
+		},
+		{
+			name:       "a test with expected_number and min returns an error",
+			entityTest: TestEntity{Type: "POWERDNS_AUTHORITATIVE", ExpectedNumber: 1, Min: 1},
+			wantErr:    true,
+		},
+		{
+			name:       "a test with exact and max returns an error",
+			entityTest: TestEntity{Type: "POWERDNS_AUTHORITATIVE", Exact: 1, Max: 2},
+			wantErr:    true,
+		},
+		{
+			name:       "a test with negative counts returns an error",
+			entityTest: TestEntity{Type: "POWERDNS_AUTHORITATIVE", Max: -1},
+			wantErr:    true,
+		},
+		{
+			name:       "a test with absent and counts returns an error",
+			entityTest: TestEntity{Type: "POWERDNS_AUTHORITATIVE", Absent: true, Exact: 1},
+			wantErr:    true,
+		},
+		{
+			name:       "a test with absent and attributes returns an error",
+			entityTest: TestEntity{Type: "POWERDNS_AUTHORITATIVE", Absent: true, NamePattern: "*"},
+			wantErr:    true,
+		},
+		{
+			name:       "a test with an invalid name_pattern returns an error",
+			entityTest: TestEntity{NamePattern: "regex:host:(port"},
+			wantErr:    true,
+		},
+		{
+			name:       "a test with an invalid tag pattern returns an error",
+			entityTest: TestEntity{Tags: map[string]string{"clusterName": "[e2e"}},
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.entityTest.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTestCompare_validate(t *testing.T) {
+	values := []CompareValue{
+		{Name: "partitions", Query: "SELECT sum(bytes) as 'bytes' FROM Metric", Key: "bytes"},
+		{Name: "topic", Query: "SELECT latest(topicBytes) as 'bytes' FROM Metric", Key: "bytes"},
+	}
+
+	tests := []struct {
+		name        string
+		compareTest TestCompare
+		wantErr     bool
+	}{
+		{
+			name:        "a test with valid relations does not return an error",
+			compareTest: TestCompare{Values: values, Relations: []CompareRelation{{Left: "partitions", Right: "topic", Relation: RelationEqual, Tolerance: 0.01}, {Left: "partitions", Right: "topic", Relation: RelationRatio, Ratio: 1}}},
+			wantErr:     false,
+		},
+		{
+			name:        "a test with a single value returns an error",
+			compareTest: TestCompare{Values: values[:1], Relations: []CompareRelation{{Left: "partitions", Right: "partitions", Relation: RelationEqual}}},
+			wantErr:     true,
+		},
+		{
+			name:        "a test with duplicated value names returns an error",
+			compareTest: TestCompare{Values: []CompareValue{values[0], values[0]}, Relations: []CompareRelation{{Left: "partitions", Right: "partitions", Relation: RelationEqual}}},
+			wantErr:     true,
+		},
+		{
+			name:        "a test with a value without key returns an error",
+			compareTest: TestCompare{Values: []CompareValue{values[0], {Name: "topic", Query: "SELECT 1 FROM Metric"}}, Relations: []CompareRelation{{Left: "partitions", Right: "topic", Relation: RelationEqual}}},
+			wantErr:     true,
+		},
+		{
+			name:        "a test without relations returns an error",
+			compareTest: TestCompare{Values: values},
+			wantErr:     true,
+		},
+		{
+			name:        "a test with a relation of unknown values returns an error",
+			compareTest: TestCompare{Values: values, Relations: []CompareRelation{{Left: "partitions", Right: "brokers", Relation: RelationEqual}}},
+			wantErr:     true,
+		},
+		{
+			name:        "a test with an unknown relation returns an error",
+			compareTest: TestCompare{Values: values, Relations: []CompareRelation{{Left: "partitions", Right: "topic", Relation: "greater_than"}}},
+			wantErr:     true,
+		},
+		{
+			name:        "a test with a ratio relation without ratio returns an error",
+			compareTest: TestCompare{Values: values, Relations: []CompareRelation{{Left: "partitions", Right: "topic", Relation: RelationRatio}}},
+			wantErr:     true,
+		},
+		{
+			name:        "a test with a negative tolerance returns an error",
+			compareTest: TestCompare{Values: values, Relations: []CompareRelation{{Left: "partitions", Right: "topic", Relation: RelationEqual, Tolerance: -1}}},
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.compareTest.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTestRelationship_validate(t *testing.T) {
+	entities := []TestEntity{
+		{Type: "KAFKABROKER", DataType: "Metric", MetricName: "kafka_broker_up"},
+		{Type: "KAFKACONTROLLER", DataType: "Metric", MetricName: "kafka_controller_up", Absent: true},
+	}
+
+	tests := []struct {
+		name             string
+		relationshipTest TestRelationship
+		wantErr          bool
+	}{
+		{
+			name:             "a test of an entity of the scenario does not return an error",
+			relationshipTest: TestRelationship{EntityType: "KAFKABROKER", Type: "CONTAINS", TargetType: "KAFKATOPIC", MinCount: 2},
+			wantErr:          false,
+		},
+		{
+			name:             "a test of an entity expected to be absent returns an error",
+			relationshipTest: TestRelationship{EntityType: "KAFKACONTROLLER", TargetType: "KAFKATOPIC"},
+			wantErr:          true,
+		},
+		{
+			name:             "a test without target_type returns an error",
+			relationshipTest: TestRelationship{EntityType: "KAFKABROKER"},
+			wantErr:          true,
+		},
+		{
+			name:             "a test with a negative min_count returns an error",
+			relationshipTest: TestRelationship{EntityType: "KAFKABROKER", TargetType: "KAFKATOPIC", MinCount: -1},
+			wantErr:          true,
+		},
+		{
+			name:             "a test with a known direction does not return an error",
+			relationshipTest: TestRelationship{EntityType: "KAFKABROKER", Type: "HOSTS", TargetType: "HOST", Direction: DirectionIncoming},
+			wantErr:          false,
+		},
+		{
+			name:             "a test with an unknown direction returns an error",
+			relationshipTest: TestRelationship{EntityType: "KAFKABROKER", TargetType: "KAFKATOPIC", Direction: "both"},
+			wantErr:          true,
+		},
+		{
+			name:             "a test of an entity without entities test returns an error",
+			relationshipTest: TestRelationship{EntityType: "KAFKACONSUMER", TargetType: "KAFKATOPIC"},
+			wantErr:          true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.relationshipTest.validate(entities); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTestMetrics_validate(t *testing.T) {
+	lowerValue := 0.0
+	upperValue := 100.0
+
+	tests := []struct {
+		name        string
+		metricsTest TestMetrics
+		wantErr     bool
+	}{
+		{
+			name:        "a test without cadence does not return an error",
+			metricsTest: TestMetrics{Source: "powerdns.yml"},
+			wantErr:     false,
+		},
+		{
+			name:        "a test with a valid cadence does not return an error",
+			metricsTest: TestMetrics{Source: "powerdns.yml", Cadence: &Cadence{Tolerance: 0.5, MaxGaps: 1}},
+			wantErr:     false,
+		},
+		{
+			name:        "a test with a negative cadence tolerance returns an error",
+			metricsTest: TestMetrics{Source: "powerdns.yml", Cadence: &Cadence{Tolerance: -0.5}},
+			wantErr:     true,
+		},
+		{
+			name:        "a test with unexpected_metrics in warn mode does not return an error",
+			metricsTest: TestMetrics{Source: "powerdns.yml", UnexpectedMetrics: &UnexpectedMetrics{Mode: UnexpectedMetricsWarn}},
+			wantErr:     false,
+		},
+		{
+			name:        "a test with unexpected_metrics without a valid mode returns an error",
+			metricsTest: TestMetrics{Source: "powerdns.yml", UnexpectedMetrics: &UnexpectedMetrics{Mode: "error"}},
+			wantErr:     true,
+		},
+		{
+			name:        "a test with glob and regex exceptions does not return an error",
+			metricsTest: TestMetrics{Source: "k8s.yml", Exceptions: Exceptions{ExceptMetrics: []Exception{{Name: "k8s.node.capacity*"}, {Name: `regex:k8s\.pod\..*`}}}},
+			wantErr:     false,
+		},
+		{
+			name:        "a test with an invalid exception pattern returns an error",
+			metricsTest: TestMetrics{Source: "k8s.yml", Exceptions: Exceptions{ExceptEntities: []Exception{{Name: "regex:K8s(Volume"}}}},
+			wantErr:     true,
+		},
+		{
+			name:        "a test with an invalid unexpected_metrics allow pattern returns an error",
+			metricsTest: TestMetrics{Source: "k8s.yml", UnexpectedMetrics: &UnexpectedMetrics{Mode: UnexpectedMetricsWarn, Allow: []string{"nr_stats[*"}}},
+			wantErr:     true,
+		},
+		{
+			name:        "a test with an invalid service_version returns an error",
+			metricsTest: TestMetrics{Source: "powerdns.yml", ServiceVersion: "latest"},
+			wantErr:     true,
+		},
+		{
+			name:        "a test with value_rules with min greater than max returns an error",
+			metricsTest: TestMetrics{Source: "powerdns.yml", ValueRules: map[string]ValueRules{"metric": {Min: &upperValue, Max: &lowerValue}}},
+			wantErr:     true,
+		},
+		{
+			name:        "a test with negative cadence max_gaps returns an error",
+			metricsTest: TestMetrics{Source: "powerdns.yml", Cadence: &Cadence{MaxGaps: -1}},
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.metricsTest.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestStep_validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		step    Step
+		wantErr bool
+	}{
+		{
+			name:    "a run step does not return an error",
+			step:    Step{Run: []string{"docker compose stop db"}},
+			wantErr: false,
+		},
+		{
+			name:    "a wait step does not return an error",
+			step:    Step{Wait: "30s"},
+			wantErr: false,
+		},
+		{
+			name:    "a tests step does not return an error",
+			step:    Step{Tests: &Tests{NRQLs: []TestNRQL{{Query: "SELECT latest(up) FROM Metric"}}}},
+			wantErr: false,
+		},
+		{
+			name:    "an empty step returns an error",
+			step:    Step{Description: "nothing to do"},
+			wantErr: true,
+		},
+		{
+			name:    "a step with run and wait returns an error",
+			step:    Step{Run: []string{"docker compose stop db"}, Wait: "30s"},
+			wantErr: true,
+		},
+		{
+			name:    "a step with an invalid wait returns an error",
+			step:    Step{Wait: "30 seconds"},
+			wantErr: true,
+		},
+		{
+			name:    "a step with a negative wait returns an error",
+			step:    Step{Wait: "-30s"},
+			wantErr: true,
+		},
+		{
+			name:    "a step with invalid tests returns an error",
+			step:    Step{Tests: &Tests{NRQLs: []TestNRQL{{}}}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.step.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTestAgentLogs_validate(t *testing.T) {
+	tests := []struct {
+		name          string
+		agentLogsTest TestAgentLogs
+		wantErr       bool
+	}{
+		{
+			name:          "a test without patterns does not return an error",
+			agentLogsTest: TestAgentLogs{},
+			wantErr:       false,
+		},
+		{
+			name: "a test with valid patterns does not return an error",
+			agentLogsTest: TestAgentLogs{
+				FailOn:      []string{"level=warn(ing)?"},
+				Allow:       []string{"can't connect to the inventory"},
+				MustContain: []string{"instance=nri-\\w+"},
+			},
+			wantErr: false,
+		},
+		{
+			name:          "a test with an invalid fail_on pattern returns an error",
+			agentLogsTest: TestAgentLogs{FailOn: []string{"level=(warn"}},
+			wantErr:       true,
+		},
+		{
+			name:          "a test with an invalid allow pattern returns an error",
+			agentLogsTest: TestAgentLogs{Allow: []string{"[inventory"}},
+			wantErr:       true,
+		},
+		{
+			name:          "a test with an invalid must_contain pattern returns an error",
+			agentLogsTest: TestAgentLogs{MustContain: []string{"nri-(\\w+"}},
+			wantErr:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.agentLogsTest.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTestProtocol_validate(t *testing.T) {
+	tests := []struct {
+		name         string
+		protocolTest TestProtocol
+		wantErr      bool
+	}{
+		{
+			name:         "a test with integration and source does not return an error",
+			protocolTest: TestProtocol{Integration: "nri-powerdns", Source: "powerdns.yml", Timeout: "1m"},
+			wantErr:      false,
+		},
+		{
+			name:         "a test without integration returns an error",
+			protocolTest: TestProtocol{Source: "powerdns.yml"},
+			wantErr:      true,
+		},
+		{
+			name:         "a test with an invalid timeout returns an error",
+			protocolTest: TestProtocol{Integration: "nri-powerdns", Timeout: "0s"},
+			wantErr:      true,
+		},
+		{
+			name:         "a test with an invalid exception returns an error",
+			protocolTest: TestProtocol{Integration: "nri-powerdns", Exceptions: Exceptions{ExceptMetrics: []Exception{{Name: "regex:powerdns_(up"}}}},
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.protocolTest.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_ParseDefinitionFileProtocolIntegration(t *testing.T) {
+	sample := `
+scenarios:
+  - integrations:
+      - name: nri-powerdns
+        binary_path: bin/nri-powerdns
+      - name: nri-prometheus
+    tests:
+      protocol:
+        - integration: %s
+`
+	spec, err := ParseDefinitionFile([]byte(fmt.Sprintf(sample, "nri-powerdns")))
+	require.NoError(t, err)
+	require.NotNil(t, spec.Scenarios[0].Tests.Protocol[0].ScenarioIntegration)
+	assert.Equal(t, "bin/nri-powerdns", spec.Scenarios[0].Tests.Protocol[0].ScenarioIntegration.BinaryPath)
+
+	_, err = ParseDefinitionFile([]byte(fmt.Sprintf(sample, "nri-mysql")))
+	assert.ErrorIs(t, err, ErrInvalidProtocolConfig)
+
+	_, err = ParseDefinitionFile([]byte(fmt.Sprintf(sample, "nri-prometheus")))
+	assert.ErrorIs(t, err, ErrInvalidProtocolConfig)
+}
+
+func Test_ParseDefinitionFileExpectedResultType(t *testing.T) {
+	sample := `
+scenarios:
+  - tests:
+      nrqls:
+        - query: "SELECT latest(state) FROM Metric"
+          expected_results:
+            - key: "latest.state"
+              type: %s
+`
+	for _, resultType := range []string{"null", "~", `"null"`, "string"} {
+		spec, err := ParseDefinitionFile([]byte(fmt.Sprintf(sample, resultType)))
+		require.NoError(t, err, resultType)
+
+		expected := ResultTypeNull
+		if resultType == "string" {
+			expected = ResultTypeString
+		}
+		assert.Equal(t, expected, spec.Scenarios[0].Tests.NRQLs[0].ExpectedResults[0].Type, resultType)
+	}
+}
+
+func TestTestExporter_validate(t *testing.T) {
+	tests := []struct {
+		name         string
+		exporterTest TestExporter
+		wantErr      bool
+	}{
+		{
+			name:         "a test with url and source does not return an error",
+			exporterTest: TestExporter{URL: "http://localhost:9121/metrics", Source: "powerdns.yml"},
+			wantErr:      false,
+		},
+		{
+			name:         "a test with url and metrics does not return an error",
+			exporterTest: TestExporter{URL: "http://localhost:9121/metrics", Metrics: []string{"powerdns_authoritative_*"}},
+			wantErr:      false,
+		},
+		{
+			name:         "a test without url returns an error",
+			exporterTest: TestExporter{Source: "powerdns.yml"},
+			wantErr:      true,
+		},
+		{
+			name:         "a test with a non http url returns an error",
+			exporterTest: TestExporter{URL: "localhost:9121/metrics", Source: "powerdns.yml"},
+			wantErr:      true,
+		},
+		{
+			name:         "a test without source nor metrics returns an error",
+			exporterTest: TestExporter{URL: "http://localhost:9121/metrics"},
+			wantErr:      true,
+		},
+		{
+			name:         "a test with an invalid metrics pattern returns an error",
+			exporterTest: TestExporter{URL: "http://localhost:9121/metrics", Metrics: []string{"regex:powerdns_(up"}},
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.exporterTest.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package spec
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+const exceptionUntilLayout = "2006-01-02"
+
+var ErrInvalidException = errors.New("invalid exception")
+
+// Exception is an entry of the except lists. It can be written as a plain name or pattern, or as a
+// mapping documenting why it is needed and until when.
+type Exception struct {
+	Name   string `yaml:"name"`
+	Reason string `yaml:"reason"`
+	Issue  string `yaml:"issue"`
+	// Until is the date, formatted as YYYY-MM-DD, after which the exception is considered expired.
+	Until string `yaml:"until"`
+}
+
+func (e *Exception) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		e.Name = value.Value
+		return nil
+	}
+
+	type plainException Exception
+	return value.Decode((*plainException)(e))
+}
+
+// Matches returns true if the name matches the pattern of the exception.
+func (e Exception) Matches(name string) bool {
+	return MatchesPattern(e.Name, name)
+}
+
+// Expired returns true if the exception has an until date before the given time.
+func (e Exception) Expired(now time.Time) bool {
+	if e.Until == "" {
+		return false
+	}
+	until, err := time.Parse(exceptionUntilLayout, e.Until)
+	if err != nil {
+		return false
+	}
+	// The exception is valid during the whole until day.
+	return now.After(until.AddDate(0, 0, 1))
+}
+
+// String returns the name of the exception followed by its reason and issue if any.
+func (e Exception) String() string {
+	description := e.Name
+	if e.Reason != "" {
+		description += fmt.Sprintf(" (reason: %s)", e.Reason)
+	}
+	if e.Issue != "" {
+		description += fmt.Sprintf(" (issue: %s)", e.Issue)
+	}
+	return description
+}
+
+func (e Exception) validate() error {
+	if e.Name == "" {
+		return fmt.Errorf("%w: missing name", ErrInvalidException)
+	}
+
+	if err := ValidatePattern(e.Name); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidException, err)
+	}
+
+	if e.Until != "" {
+		if _, err := time.Parse(exceptionUntilLayout, e.Until); err != nil {
+			return fmt.Errorf("%w: %q until date must be formatted as YYYY-MM-DD: %s", ErrInvalidException, e.Name, err)
+		}
+	}
+	return nil
+}
+
+// ExceptionNames returns the names or patterns of the exceptions.
+func ExceptionNames(exceptions []Exception) []string {
+	names := make([]string, 0, len(exceptions))
+	for _, exception := range exceptions {
+		names = append(names, exception.Name)
+	}
+	return names
+}
+
+func (e Exceptions) validate() error {
+	for _, exceptions := range [][]Exception{e.ExceptEntities, e.ExceptMetrics, e.ExceptDimensions} {
+		for _, exception := range exceptions {
+			if err := exception.validate(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
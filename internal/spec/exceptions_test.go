@@ -0,0 +1,74 @@
+package spec
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_ParseExceptionsFileStructured(t *testing.T) {
+	sample := `
+except_metrics:
+- metric_a
+- name: metric_b*
+  reason: only reported by the enterprise edition
+  issue: https://github.com/newrelic/nri-powerdns/issues/1
+  until: 2030-01-31
+`
+	exceptions, err := ParseExceptionsFile([]byte(sample))
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]Exception{
+			{Name: "metric_a"},
+			{
+				Name:   "metric_b*",
+				Reason: "only reported by the enterprise edition",
+				Issue:  "https://github.com/newrelic/nri-powerdns/issues/1",
+				Until:  "2030-01-31",
+			},
+		},
+		exceptions.ExceptMetrics)
+	assert.Equal(t, []string{"metric_a", "metric_b*"}, ExceptionNames(exceptions.ExceptMetrics))
+	assert.True(t, exceptions.ExceptMetrics[1].Matches("metric_bytes"))
+}
+
+func Test_ParseExceptionsFileInvalidException(t *testing.T) {
+	samples := map[string]string{
+		"missing name": `
+except_metrics:
+- reason: no name
+`,
+		"invalid until": `
+except_entities:
+- name: ENTITY_A
+  until: 31/01/2030
+`,
+	}
+	for name, sample := range samples {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseExceptionsFile([]byte(sample))
+			assert.ErrorIs(t, err, ErrInvalidException)
+		})
+	}
+}
+
+func TestException_Expired(t *testing.T) {
+	now := time.Date(2030, 1, 31, 12, 0, 0, 0, time.UTC)
+
+	assert.False(t, Exception{Name: "metric"}.Expired(now))
+	assert.False(t, Exception{Name: "metric", Until: "2030-01-31"}.Expired(now))
+	assert.False(t, Exception{Name: "metric", Until: "2030-02-01"}.Expired(now))
+	assert.True(t, Exception{Name: "metric", Until: "2030-01-30"}.Expired(now))
+}
+
+func TestException_String(t *testing.T) {
+	assert.Equal(t, "metric", Exception{Name: "metric"}.String())
+	assert.Equal(
+		t,
+		"metric (reason: not supported) (issue: https://issue)",
+		Exception{Name: "metric", Reason: "not supported", Issue: "https://issue"}.String(),
+	)
+}
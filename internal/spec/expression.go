@@ -0,0 +1,71 @@
+package spec
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/cel-go/cel"
+	"github.com/newrelic/newrelic-client-go/pkg/nrdb"
+)
+
+// assertionCostLimit bounds the evaluation cost of the assert expressions, so a complex expression
+// over many results cannot block the runner.
+const assertionCostLimit = 1000000
+
+const assertionResultsVariable = "results"
+
+var ErrInvalidAssertion = errors.New("invalid assert expression")
+
+// Assertion is a compiled `assert` expression of a NRQL test, written in CEL (https://github.com/google/cel-spec)
+// and evaluated against the rows of the query results, available as the `results` list.
+type Assertion struct {
+	expr    string
+	program cel.Program
+}
+
+func CompileAssertion(expr string) (*Assertion, error) {
+	env, err := cel.NewEnv(
+		cel.Variable(assertionResultsVariable, cel.ListType(cel.MapType(cel.StringType, cel.DynType))),
+		cel.CrossTypeNumericComparisons(true),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	ast, issues := env.Compile(expr)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidAssertion, issues.Err())
+	}
+	if ast.OutputType() != cel.BoolType {
+		return nil, fmt.Errorf("%w: %q must return a bool, got %s", ErrInvalidAssertion, expr, ast.OutputType())
+	}
+
+	program, err := env.Program(ast, cel.CostLimit(assertionCostLimit))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidAssertion, err)
+	}
+	return &Assertion{expr: expr, program: program}, nil
+}
+
+// Evaluate returns the result of the expression for the rows of the query results.
+func (a *Assertion) Evaluate(results []nrdb.NRDBResult) (bool, error) {
+	rows := make([]interface{}, 0, len(results))
+	for _, row := range results {
+		rows = append(rows, map[string]interface{}(row))
+	}
+
+	out, _, err := a.program.Eval(map[string]interface{}{assertionResultsVariable: rows})
+	if err != nil {
+		return false, fmt.Errorf("evaluating %q: %w", a.expr, err)
+	}
+
+	value, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("evaluating %q: expected a bool, got %v", a.expr, out.Value())
+	}
+	return value, nil
+}
+
+func (a *Assertion) String() string {
+	return a.expr
+}
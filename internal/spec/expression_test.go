@@ -0,0 +1,100 @@
+package spec
+
+import (
+	"testing"
+
+	"github.com/newrelic/newrelic-client-go/pkg/nrdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompileAssertion(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		wantErr bool
+	}{
+		{
+			name:    "a bool expression over the results compiles",
+			expr:    `results[0]["Pods Total"] >= 1 && results.size() == 1`,
+			wantErr: false,
+		},
+		{
+			name:    "an expression with macros compiles",
+			expr:    `results.all(r, r.count > 0) && results.exists(r, r.facet == "orders")`,
+			wantErr: false,
+		},
+		{
+			name:    "an expression with a syntax error returns an error",
+			expr:    `results[0]["Pods Total"] >=`,
+			wantErr: true,
+		},
+		{
+			name:    "an expression with undeclared variables returns an error",
+			expr:    `rows.size() == 1`,
+			wantErr: true,
+		},
+		{
+			name:    "an expression not returning a bool returns an error",
+			expr:    `results.size()`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CompileAssertion(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CompileAssertion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAssertion_Evaluate(t *testing.T) {
+	results := []nrdb.NRDBResult{
+		{"facet": "orders", "count": 10.0, "state": "up"},
+		{"facet": "payments", "count": 3.0, "state": "up"},
+	}
+
+	tests := []struct {
+		name     string
+		expr     string
+		expected bool
+		wantErr  bool
+	}{
+		{
+			name:     "when the expression holds it should return true",
+			expr:     `results.size() == 2 && results[0].count >= 10 && results.all(r, r.state == "up")`,
+			expected: true,
+		},
+		{
+			name:     "when the expression does not hold it should return false",
+			expr:     `results.exists(r, r.count > 100)`,
+			expected: false,
+		},
+		{
+			name:    "when a key is missing it should return an error",
+			expr:    `results[0]["missing"] == 1`,
+			wantErr: true,
+		},
+		{
+			name:    "when a row is out of range it should return an error",
+			expr:    `results[5].count == 1`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertion, err := CompileAssertion(tt.expr)
+			require.NoError(t, err)
+
+			value, err := assertion.Evaluate(results)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, value)
+		})
+	}
+}
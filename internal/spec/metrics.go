@@ -1,6 +1,13 @@
 package spec
 
-import "gopkg.in/yaml.v3"
+import (
+	"errors"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+var ErrInvalidMetricsFile = errors.New("invalid metrics file")
 
 type Metrics struct {
 	Entities []Entity `yaml:"entities"`
@@ -12,7 +19,112 @@ type Entity struct {
 }
 
 type Metric struct {
-	Name string `yaml:"name"`
+	Name                 string                `yaml:"name"`
+	Type                 string                `yaml:"type"`
+	Unit                 string                `yaml:"unit"`
+	DefaultResolution    int                   `yaml:"defaultResolution"`
+	Dimensions           []Dimension           `yaml:"dimensions"`
+	MigrationInformation *MigrationInformation `yaml:"migrationInformation"`
+	// MinVersion is the first version of the monitored service reporting the metric.
+	MinVersion string `yaml:"min_version"`
+	// MaxVersion is the last version of the monitored service reporting the metric.
+	MaxVersion string `yaml:"max_version"`
+	// ValueRules are the sanity checks of the values reported for the metric.
+	ValueRules *ValueRules `yaml:"value_rules"`
+}
+
+// ValueRules are sanity checks on the values of a metric during the scenario, evaluated for each entity.
+type ValueRules struct {
+	NonNegative bool     `yaml:"non_negative"`
+	NonZero     bool     `yaml:"non_zero"`
+	Min         *float64 `yaml:"min"`
+	Max         *float64 `yaml:"max"`
+	MustChange  bool     `yaml:"must_change"`
+}
+
+// Validate returns an error if the rules cannot be satisfied.
+func (vr ValueRules) Validate() error {
+	if vr.Min != nil && vr.Max != nil && *vr.Min > *vr.Max {
+		return fmt.Errorf("min %f is greater than max %f", *vr.Min, *vr.Max)
+	}
+	return nil
+}
+
+// SupportsVersion returns true if the metric is reported by the given version of the monitored service,
+// that is, the version is not lower than MinVersion nor greater than MaxVersion. MaxVersion includes all the
+// versions it is a prefix of, so `4.5` includes `4.5.3`.
+func (m Metric) SupportsVersion(version string) (bool, error) {
+	if m.MinVersion != "" {
+		cmp, err := CompareVersions(version, m.MinVersion)
+		if err != nil {
+			return false, fmt.Errorf("metric %s min_version: %w", m.Name, err)
+		}
+		if cmp < 0 {
+			return false, nil
+		}
+	}
+
+	if m.MaxVersion != "" {
+		cmp, err := CompareVersionPrefix(version, m.MaxVersion)
+		if err != nil {
+			return false, fmt.Errorf("metric %s max_version: %w", m.Name, err)
+		}
+		if cmp > 0 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (m Metric) validateVersions() error {
+	if m.MinVersion != "" {
+		if err := ValidateVersion(m.MinVersion); err != nil {
+			return fmt.Errorf("min_version of %s: %w", m.Name, err)
+		}
+	}
+	if m.MaxVersion != "" {
+		if err := ValidateVersion(m.MaxVersion); err != nil {
+			return fmt.Errorf("max_version of %s: %w", m.Name, err)
+		}
+	}
+	return nil
+}
+
+// DimensionNames returns the names of the dimensions declared for the metric.
+func (m Metric) DimensionNames() []string {
+	names := make([]string, 0, len(m.Dimensions))
+	for _, dimension := range m.Dimensions {
+		names = append(names, dimension.Name)
+	}
+	return names
+}
+
+type Dimension struct {
+	Name                 string                `yaml:"name"`
+	MigrationInformation *MigrationInformation `yaml:"migrationInformation"`
+}
+
+// MigrationInformation maps a dimensional metric or dimension to the attributes reported in legacy samples.
+type MigrationInformation struct {
+	LegacyEventType  string   `yaml:"legacyEventType"`
+	LegacyEventTypes []string `yaml:"legacyEventTypes"`
+	LegacyNames      []string `yaml:"legacyNames"`
+}
+
+// IsLegacyEventType returns true if the legacy names are reported in the given event type.
+func (mi *MigrationInformation) IsLegacyEventType(eventType string) bool {
+	if mi == nil || eventType == "" {
+		return false
+	}
+	if mi.LegacyEventType == eventType {
+		return true
+	}
+	for _, legacyEventType := range mi.LegacyEventTypes {
+		if legacyEventType == eventType {
+			return true
+		}
+	}
+	return false
 }
 
 func ParseMetricsFile(content []byte) (*Metrics, error) {
@@ -20,5 +132,19 @@ func ParseMetricsFile(content []byte) (*Metrics, error) {
 	if err := yaml.Unmarshal(content, specMetrics); err != nil {
 		return nil, err
 	}
+
+	for _, entity := range specMetrics.Entities {
+		for _, metric := range entity.Metrics {
+			if err := metric.validateVersions(); err != nil {
+				return nil, fmt.Errorf("%w: %s", ErrInvalidMetricsFile, err)
+			}
+			if metric.ValueRules == nil {
+				continue
+			}
+			if err := metric.ValueRules.Validate(); err != nil {
+				return nil, fmt.Errorf("%w: value_rules of %s: %s", ErrInvalidMetricsFile, metric.Name, err)
+			}
+		}
+	}
 	return specMetrics, nil
 }
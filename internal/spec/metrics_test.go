@@ -12,3 +12,119 @@ func Test_ParseMetricsFile(t *testing.T) {
 	assert.Nil(t, err)
 	assert.NotNil(t, spec.Entities)
 }
+
+func Test_ParseMetricsFileValueRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		sample  string
+		wantErr bool
+	}{
+		{
+			name: "valid value rules do not return an error",
+			sample: `
+entities:
+  - entityType: PowerDNS
+    metrics:
+      - name: powerdns_authoritative_uptime_seconds
+        value_rules:
+          min: 0
+          max: 100
+`,
+			wantErr: false,
+		},
+		{
+			name: "value rules with min greater than max return an error",
+			sample: `
+entities:
+  - entityType: PowerDNS
+    metrics:
+      - name: powerdns_authoritative_uptime_seconds
+        value_rules:
+          min: 100
+          max: 0
+`,
+			wantErr: true,
+		},
+		{
+			name: "valid versions do not return an error",
+			sample: `
+entities:
+  - entityType: PowerDNS
+    metrics:
+      - name: powerdns_recursor_almost_expired_tasks
+        min_version: "4.5"
+        max_version: "v5.0.1"
+`,
+			wantErr: false,
+		},
+		{
+			name: "invalid min version returns an error",
+			sample: `
+entities:
+  - entityType: PowerDNS
+    metrics:
+      - name: powerdns_recursor_almost_expired_tasks
+        min_version: "latest"
+`,
+			wantErr: true,
+		},
+		{
+			name: "invalid max version returns an error",
+			sample: `
+entities:
+  - entityType: PowerDNS
+    metrics:
+      - name: powerdns_recursor_almost_expired_tasks
+        max_version: "4.x"
+`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseMetricsFile([]byte(tt.sample))
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidMetricsFile)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func Test_ParseMetricsFileMigrationInformation(t *testing.T) {
+	var sample = `
+entities:
+  - entityType: KafkaBroker
+    metrics:
+      - name: kafka.broker.bytesWrittenToTopicPerSecond
+        migrationInformation:
+          legacyEventType: KafkaBrokerSample
+          legacyNames:
+            - broker.bytesWrittenToTopicPerSecond
+            - topic.bytesWritten
+        dimensions:
+          - name: kafka.topic
+            migrationInformation:
+              legacyNames:
+                - topic
+              legacyEventTypes:
+                - KafkaBrokerSample
+`
+	spec, err := ParseMetricsFile([]byte(sample))
+	assert.Nil(t, err)
+
+	metric := spec.Entities[0].Metrics[0]
+	assert.True(t, metric.MigrationInformation.IsLegacyEventType("KafkaBrokerSample"))
+	assert.False(t, metric.MigrationInformation.IsLegacyEventType("KafkaTopicSample"))
+	assert.Equal(t, []string{"broker.bytesWrittenToTopicPerSecond", "topic.bytesWritten"}, metric.MigrationInformation.LegacyNames)
+
+	dimension := metric.Dimensions[0]
+	assert.Equal(t, "kafka.topic", dimension.Name)
+	assert.True(t, dimension.MigrationInformation.IsLegacyEventType("KafkaBrokerSample"))
+	assert.Equal(t, []string{"topic"}, dimension.MigrationInformation.LegacyNames)
+
+	assert.False(t, dimension.MigrationInformation.IsLegacyEventType(""))
+	var nilInformation *MigrationInformation
+	assert.False(t, nilInformation.IsLegacyEventType("KafkaBrokerSample"))
+}
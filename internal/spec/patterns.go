@@ -0,0 +1,49 @@
+package spec
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// regexPatternPrefix marks a pattern as a regular expression instead of a glob.
+const regexPatternPrefix = "regex:"
+
+// MatchesPattern returns true if the value matches the pattern, which can be an exact name, a glob
+// (i.e. `k8s.node.capacity*`) or a regular expression prefixed by `regex:` that must match the whole value.
+// Invalid patterns never match, they are reported by ValidatePattern when parsing the spec.
+func MatchesPattern(pattern, value string) bool {
+	if strings.HasPrefix(pattern, regexPatternPrefix) {
+		re, err := regexp.Compile(anchorRegex(strings.TrimPrefix(pattern, regexPatternPrefix)))
+		if err != nil {
+			return false
+		}
+		return re.MatchString(value)
+	}
+
+	matched, err := path.Match(pattern, value)
+	if err != nil {
+		return false
+	}
+	return matched
+}
+
+// ValidatePattern returns an error if the glob or regular expression of the pattern is malformed.
+func ValidatePattern(pattern string) error {
+	if strings.HasPrefix(pattern, regexPatternPrefix) {
+		if _, err := regexp.Compile(anchorRegex(strings.TrimPrefix(pattern, regexPatternPrefix))); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		return nil
+	}
+
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	return nil
+}
+
+func anchorRegex(expr string) string {
+	return "^(?:" + expr + ")$"
+}
@@ -0,0 +1,37 @@
+package spec
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchesPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		value   string
+		matches bool
+	}{
+		{pattern: "k8s.node.capacity", value: "k8s.node.capacity", matches: true},
+		{pattern: "k8s.node.capacity", value: "k8s.node.capacityCpuCores", matches: false},
+		{pattern: "k8s.node.capacity*", value: "k8s.node.capacityCpuCores", matches: true},
+		{pattern: "k8s.node.capacity*", value: "k8s.node.allocatableCpuCores", matches: false},
+		{pattern: "K8s?olume", value: "K8sVolume", matches: true},
+		{pattern: `regex:k8s\.node\.(capacity|allocatable).*`, value: "k8s.node.allocatableHugepages2Mi", matches: true},
+		{pattern: `regex:capacity`, value: "k8s.node.capacityCpuCores", matches: false},
+		{pattern: `regex:k8s\.node\.(capacity`, value: "k8s.node.capacityCpuCores", matches: false},
+		{pattern: "k8s.node.[capacity", value: "k8s.node.capacityCpuCores", matches: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+"_"+tt.value, func(t *testing.T) {
+			assert.Equal(t, tt.matches, MatchesPattern(tt.pattern, tt.value))
+		})
+	}
+}
+
+func TestValidatePattern(t *testing.T) {
+	assert.NoError(t, ValidatePattern("k8s.node.capacity*"))
+	assert.NoError(t, ValidatePattern(`regex:k8s\.node\..*`))
+	assert.Error(t, ValidatePattern("k8s.node.[capacity"))
+	assert.Error(t, ValidatePattern(`regex:k8s\.node\.(capacity`))
+}
@@ -0,0 +1,89 @@
+package spec
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidVersion = errors.New("invalid version")
+
+// CompareVersions compares two dotted numeric versions like `4.5.1`, returning -1, 0 or 1.
+// A leading `v` and any pre-release or build suffix (`-beta`, `+build`) are ignored, and missing
+// components are considered 0, so `4.5` equals `4.5.0`.
+func CompareVersions(a, b string) (int, error) {
+	aParts, err := parseVersion(a)
+	if err != nil {
+		return 0, err
+	}
+	bParts, err := parseVersion(b)
+	if err != nil {
+		return 0, err
+	}
+	return compareParts(aParts, bParts), nil
+}
+
+// CompareVersionPrefix compares a version with a prefix like CompareVersions, but only up to the
+// components of the prefix, so `4.5.3` equals the prefix `4.5` while `4.6` is greater.
+func CompareVersionPrefix(version, prefix string) (int, error) {
+	versionParts, err := parseVersion(version)
+	if err != nil {
+		return 0, err
+	}
+	prefixParts, err := parseVersion(prefix)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(versionParts) > len(prefixParts) {
+		versionParts = versionParts[:len(prefixParts)]
+	}
+	return compareParts(versionParts, prefixParts), nil
+}
+
+func compareParts(aParts, bParts []int) int {
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		var aPart, bPart int
+		if i < len(aParts) {
+			aPart = aParts[i]
+		}
+		if i < len(bParts) {
+			bPart = bParts[i]
+		}
+
+		if aPart < bPart {
+			return -1
+		}
+		if aPart > bPart {
+			return 1
+		}
+	}
+	return 0
+}
+
+// ValidateVersion returns an error if the version cannot be compared.
+func ValidateVersion(version string) error {
+	_, err := parseVersion(version)
+	return err
+}
+
+func parseVersion(version string) ([]int, error) {
+	trimmed := strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if i := strings.IndexAny(trimmed, "-+"); i >= 0 {
+		trimmed = trimmed[:i]
+	}
+	if trimmed == "" {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidVersion, version)
+	}
+
+	var parts []int
+	for _, component := range strings.Split(trimmed, ".") {
+		part, err := strconv.Atoi(component)
+		if err != nil || part < 0 {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidVersion, version)
+		}
+		parts = append(parts, part)
+	}
+	return parts, nil
+}
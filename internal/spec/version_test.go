@@ -0,0 +1,87 @@
+package spec
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{a: "4.5.1", b: "4.5.1", expected: 0},
+		{a: "4.5", b: "4.5.0", expected: 0},
+		{a: "v4.5.0", b: "4.5", expected: 0},
+		{a: "4.5.0-beta1", b: "4.5", expected: 0},
+		{a: "4.10", b: "4.9", expected: 1},
+		{a: "4.9.3", b: "5", expected: -1},
+		{a: "3", b: "2.99.99", expected: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+"_"+tt.b, func(t *testing.T) {
+			cmp, err := CompareVersions(tt.a, tt.b)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, cmp)
+		})
+	}
+
+	_, err := CompareVersions("4.x", "4.5")
+	assert.ErrorIs(t, err, ErrInvalidVersion)
+	_, err = CompareVersions("4.5", "")
+	assert.ErrorIs(t, err, ErrInvalidVersion)
+}
+
+func TestCompareVersionPrefix(t *testing.T) {
+	tests := []struct {
+		version, prefix string
+		expected        int
+	}{
+		{version: "4.5.3", prefix: "4.5", expected: 0},
+		{version: "4.5", prefix: "4.5.0", expected: 0},
+		{version: "4", prefix: "4.5", expected: -1},
+		{version: "4.6.0", prefix: "4.5", expected: 1},
+		{version: "v4.5.10-beta1", prefix: "4", expected: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version+"_"+tt.prefix, func(t *testing.T) {
+			cmp, err := CompareVersionPrefix(tt.version, tt.prefix)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, cmp)
+		})
+	}
+
+	_, err := CompareVersionPrefix("4.5", "4.x")
+	assert.ErrorIs(t, err, ErrInvalidVersion)
+}
+
+func TestMetric_SupportsVersion(t *testing.T) {
+	tests := []struct {
+		name      string
+		metric    Metric
+		version   string
+		supported bool
+	}{
+		{name: "without range", metric: Metric{Name: "m"}, version: "1.0", supported: true},
+		{name: "at min version", metric: Metric{Name: "m", MinVersion: "4.5"}, version: "4.5.0", supported: true},
+		{name: "below min version", metric: Metric{Name: "m", MinVersion: "4.5"}, version: "4.4.9", supported: false},
+		{name: "below max version", metric: Metric{Name: "m", MaxVersion: "5.0"}, version: "4.9.3", supported: true},
+		{name: "at max version", metric: Metric{Name: "m", MaxVersion: "5.0"}, version: "5", supported: true},
+		{name: "patch of max version", metric: Metric{Name: "m", MaxVersion: "5.0"}, version: "5.0.1", supported: true},
+		{name: "above max version", metric: Metric{Name: "m", MaxVersion: "5.0"}, version: "5.1", supported: false},
+		{name: "above major max version", metric: Metric{Name: "m", MaxVersion: "4"}, version: "5.0.1", supported: false},
+		{name: "inside range", metric: Metric{Name: "m", MinVersion: "4.5", MaxVersion: "5.0"}, version: "4.7", supported: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			supported, err := tt.metric.SupportsVersion(tt.version)
+			require.NoError(t, err)
+			assert.Equal(t, tt.supported, supported)
+		})
+	}
+
+	_, err := Metric{Name: "m", MinVersion: "latest"}.SupportsVersion("4.5")
+	assert.ErrorIs(t, err, ErrInvalidVersion)
+}
@@ -3,6 +3,7 @@ package main
 import (
 	_ "embed"
 	"flag"
+	"os"
 
 	e2e "github.com/newrelic/newrelic-integration-e2e-action/internal"
 	"github.com/newrelic/newrelic-integration-e2e-action/internal/newrelic"
@@ -11,20 +12,25 @@ import (
 )
 
 const (
-	flagSpecPath      = "spec_path"
-	flagVerboseMode   = "verbose_mode"
-	flagApiKey        = "api_key"
-	flagAccountID     = "account_id"
-	flagLicenseKey    = "license_key"
-	flagAgentEnabled  = "agent_enabled"
-	flagRetryAttempts = "retry_attempts"
-	flagRetrySecons   = "retry_seconds"
-	flagCommitSha     = "commit_sha"
-	flagRegion        = "region"
-	flagScenarioTag   = "scenario_tag"
+	flagSpecPath        = "spec_path"
+	flagVerboseMode     = "verbose_mode"
+	flagApiKey          = "api_key"
+	flagAccountID       = "account_id"
+	flagLicenseKey      = "license_key"
+	flagAgentEnabled    = "agent_enabled"
+	flagRetryAttempts   = "retry_attempts"
+	flagRetrySecons     = "retry_seconds"
+	flagCommitSha       = "commit_sha"
+	flagRegion          = "region"
+	flagScenarioTag     = "scenario_tag"
+	flagKeepOnFailure   = "keep_on_failure"
+	flagUpdateSnapshots = "update_snapshots"
+
+	cleanupCommand  = "cleanup"
+	protocolCommand = "protocol"
 )
 
-func processCliArgs() (string, string, bool, string, int, int, int, string, logrus.Level, string, string) {
+func processCliArgs() (string, string, bool, string, int, int, int, string, logrus.Level, string, string, bool, bool) {
 	specsPath := flag.String(flagSpecPath, "", "Path to the spec file")
 	licenseKey := flag.String(flagLicenseKey, "", "New Relic License Key")
 	agentEnabled := flag.Bool(flagAgentEnabled, true, "If false the agent is not run")
@@ -36,6 +42,8 @@ func processCliArgs() (string, string, bool, string, int, int, int, string, logr
 	commitSha := flag.String(flagCommitSha, "", "Current commit sha")
 	region := flag.String(flagRegion, "", "Current commit sha")
 	scenarioTag := flag.String(flagScenarioTag, "", "E2e testing scenario tag")
+	keepOnFailure := flag.Bool(flagKeepOnFailure, false, "If true the environment of a failing scenario is not torn down")
+	updateSnapshots := flag.Bool(flagUpdateSnapshots, false, "If true the NRQL snapshots are written instead of compared")
 	flag.Parse()
 
 	if *licenseKey == "" {
@@ -55,13 +63,79 @@ func processCliArgs() (string, string, bool, string, int, int, int, string, logr
 	if *verboseMode {
 		logLevel = logrus.DebugLevel
 	}
-	return *licenseKey, *specsPath, *agentEnabled, *apiKey, *accountID, *retryAttempts, *retrySeconds, *commitSha, logLevel, *region, *scenarioTag
+	return *licenseKey, *specsPath, *agentEnabled, *apiKey, *accountID, *retryAttempts, *retrySeconds, *commitSha, logLevel, *region, *scenarioTag, *keepOnFailure, *updateSnapshots
+}
+
+// runCleanup tears down the environments kept by previous runs executed with keep_on_failure.
+func runCleanup(args []string) {
+	flags := flag.NewFlagSet(cleanupCommand, flag.ExitOnError)
+	scenarioTag := flags.String(flagScenarioTag, "", "Tag of the kept scenario to clean up, all of them if empty")
+	verboseMode := flags.Bool(flagVerboseMode, false, "If true the debug level is enabled")
+	_ = flags.Parse(args)
+
+	logger := logrus.New()
+	if *verboseMode {
+		logger.SetLevel(logrus.DebugLevel)
+	}
+
+	if err := runtime.Cleanup(logger, *scenarioTag); err != nil {
+		logger.Fatal(err)
+	}
+
+	logger.Info("cleanup completed successfully!")
+}
+
+// runProtocol executes only the protocol tests of the spec, which need neither the New Relic credentials
+// nor the agent.
+func runProtocol(args []string) {
+	flags := flag.NewFlagSet(protocolCommand, flag.ExitOnError)
+	specsPath := flags.String(flagSpecPath, "", "Path to the spec file")
+	verboseMode := flags.Bool(flagVerboseMode, false, "If true the debug level is enabled")
+	commitSha := flags.String(flagCommitSha, "", "Current commit sha")
+	scenarioTag := flags.String(flagScenarioTag, "", "E2e testing scenario tag")
+	_ = flags.Parse(args)
+
+	if *specsPath == "" {
+		logrus.Fatalf("missing required spec_path")
+	}
+
+	logLevel := logrus.InfoLevel
+	if *verboseMode {
+		logLevel = logrus.DebugLevel
+	}
+
+	s, err := e2e.NewSettings(
+		e2e.SettingsWithSpecPath(*specsPath),
+		e2e.SettingsWithLogLevel(logLevel),
+		e2e.SettingsWithAgentEnabled(false),
+		e2e.SettingsWithCommitSha(*commitSha),
+		e2e.SettingsWithScenarioTag(*scenarioTag),
+	)
+	if err != nil {
+		logrus.Fatalf("error loading settings: %s", err)
+	}
+
+	if err := runtime.NewRunner(nil, s).RunProtocol(); err != nil {
+		logrus.Fatal(err)
+	}
+
+	logrus.Info("protocol tests completed successfully!")
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == cleanupCommand {
+		runCleanup(os.Args[2:])
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == protocolCommand {
+		runProtocol(os.Args[2:])
+		return
+	}
+
 	logrus.Info("running e2e")
 
-	licenseKey, specsPath, agentEnabled, apiKey, accountID, retryAttempts, retrySeconds, commitSha, logLevel, region, scenarioTag := processCliArgs()
+	licenseKey, specsPath, agentEnabled, apiKey, accountID, retryAttempts, retrySeconds, commitSha, logLevel, region, scenarioTag, keepOnFailure, updateSnapshots := processCliArgs()
 	s, err := e2e.NewSettings(
 		e2e.SettingsWithSpecPath(specsPath),
 		e2e.SettingsWithLogLevel(logLevel),
@@ -74,6 +148,8 @@ func main() {
 		e2e.SettingsWithCommitSha(commitSha),
 		e2e.SettingsWithRegion(region),
 		e2e.SettingsWithScenarioTag(scenarioTag),
+		e2e.SettingsWithKeepOnFailure(keepOnFailure),
+		e2e.SettingsWithUpdateSnapshots(updateSnapshots),
 	)
 	if err != nil {
 		logrus.Fatalf("error loading settings: %s", err)
@@ -99,7 +175,12 @@ func createRunner(settings e2e.Settings) (*runtime.Runner, error) {
 	runtimeTester := []runtime.Tester{
 		runtime.NewEntitiesTester(nrClient, settings.Logger()),
 		runtime.NewMetricsTester(nrClient, settings.Logger(), settings.SpecParentDir()),
-		runtime.NewNRQLTester(nrClient, settings.Logger()),
+		runtime.NewNRQLTester(nrClient, settings.Logger(), settings.SpecParentDir(), settings.UpdateSnapshots()),
+		runtime.NewLogsTester(nrClient, settings.Logger()),
+		runtime.NewRelationshipsTester(nrClient, settings.Logger()),
+		runtime.NewCompareTester(nrClient, settings.Logger()),
+		runtime.NewProtocolTester(settings.Logger(), settings.SpecParentDir()),
+		runtime.NewExportersTester(nrClient, settings.Logger(), settings.SpecParentDir()),
 	}
 
 	return runtime.NewRunner(runtimeTester, settings), nil
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"strings"
+	"time"
 )
 
 const (
@@ -47,25 +49,52 @@ func Build(path, container string, envVars map[string]string) error {
 	return cmd.Run()
 }
 
-func Logs(path, containerName string) string {
-	containerID := getContainerID(path, containerName)
+// Logs returns the logs of the container written between since and until, a zero time leaves the bound open.
+func Logs(path, containerName string, since, until time.Time) (string, error) {
+	containerID, err := getContainerID(path, containerName)
+	if err != nil {
+		return "", err
+	}
 
-	args := []string{"logs", containerID}
+	args := []string{"logs"}
+	if !since.IsZero() {
+		args = append(args, "--since", since.Format(time.RFC3339Nano))
+	}
+	if !until.IsZero() {
+		args = append(args, "--until", until.Format(time.RFC3339Nano))
+	}
+	args = append(args, containerID)
 	cmd := exec.Command(dockerBin, args...)
 	stdout, err := cmd.Output()
-	if ee, ok := err.(*exec.ExitError); ok {
-		fmt.Print(string(ee.Stderr))
+	if err != nil {
+		return "", commandError("getting logs of container "+containerName, err)
 	}
-	return string(stdout)
+	return string(stdout), nil
 }
 
-func getContainerID(path, containerName string) string {
+func getContainerID(path, containerName string) (string, error) {
 	const shortContainerIDLength = 12
 	args := []string{"compose", "-f", path, "ps", "-q", containerName}
 	cmd := exec.Command(dockerBin, args...)
-	containerID, _ := cmd.Output()
+	output, err := cmd.Output()
+	if err != nil {
+		return "", commandError("getting id of container "+containerName, err)
+	}
+
+	containerID := strings.TrimSpace(string(output))
+	if containerID == "" {
+		return "", fmt.Errorf("container %s is not running", containerName)
+	}
 	if len(containerID) > shortContainerIDLength {
-		return string(containerID)[:shortContainerIDLength]
+		return containerID[:shortContainerIDLength], nil
+	}
+	return containerID, nil
+}
+
+// commandError adds the stderr of a failed command to its error.
+func commandError(action string, err error) error {
+	if ee, ok := err.(*exec.ExitError); ok {
+		return fmt.Errorf("%s: %w: %s", action, err, strings.TrimSpace(string(ee.Stderr)))
 	}
-	return string(containerID)
+	return fmt.Errorf("%s: %w", action, err)
 }
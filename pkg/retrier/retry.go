@@ -1,12 +1,41 @@
 package retrier
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
+// permanentError is an error that retrying cannot fix.
+type permanentError struct {
+	err error
+}
+
+func (pe permanentError) Error() string {
+	return pe.err.Error()
+}
+
+func (pe permanentError) Unwrap() error {
+	return pe.err
+}
+
+// Permanent wraps an error so Retry stops retrying when it is returned.
+func Permanent(err error) error {
+	return permanentError{err: err}
+}
+
+func isPermanent(errs []error) bool {
+	for _, err := range errs {
+		var pe permanentError
+		if errors.As(err, &pe) {
+			return true
+		}
+	}
+	return false
+}
+
 func Retry(log *logrus.Logger, attempts int, sleep time.Duration, f func() []error) error {
 
 	var errors []error
@@ -21,6 +50,9 @@ func Retry(log *logrus.Logger, attempts int, sleep time.Duration, f func() []err
 		for _, err := range errors {
 			log.Error(err)
 		}
+		if isPermanent(errors) {
+			return fmt.Errorf("after %d attempts, stopped by permanent errors: %v", i+1, errors)
+		}
 		time.Sleep(sleep)
 	}
 	return fmt.Errorf("after %d attempts, last errors: %v", attempts, errors)
@@ -0,0 +1,100 @@
+package retrier
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errTest = errors.New("test error")
+
+func TestPermanent(t *testing.T) {
+	err := Permanent(errTest)
+	assert.ErrorIs(t, err, errTest)
+	assert.Equal(t, errTest.Error(), err.Error())
+}
+
+func Test_isPermanent(t *testing.T) {
+	tests := []struct {
+		name     string
+		errs     []error
+		expected bool
+	}{
+		{
+			name:     "when there are no errors",
+			errs:     nil,
+			expected: false,
+		},
+		{
+			name:     "when no error is permanent",
+			errs:     []error{errTest, fmt.Errorf("wrapped: %w", errTest)},
+			expected: false,
+		},
+		{
+			name:     "when one of the errors is permanent",
+			errs:     []error{errTest, Permanent(errTest)},
+			expected: true,
+		},
+		{
+			name:     "when a permanent error is wrapped",
+			errs:     []error{fmt.Errorf("wrapped: %w", Permanent(errTest))},
+			expected: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, isPermanent(tt.errs))
+		})
+	}
+}
+
+func TestRetry(t *testing.T) {
+	log := logrus.New()
+	log.SetOutput(ioutil.Discard)
+
+	tests := []struct {
+		name             string
+		errs             []error
+		expectedAttempts int
+		expectedErr      string
+	}{
+		{
+			name:             "when there are no errors it should not retry",
+			errs:             nil,
+			expectedAttempts: 1,
+		},
+		{
+			name:             "when the errors are not permanent it should retry all the attempts",
+			errs:             []error{errTest},
+			expectedAttempts: 3,
+			expectedErr:      "after 3 attempts, last errors: [test error]",
+		},
+		{
+			name:             "when an error is permanent it should stop retrying",
+			errs:             []error{errTest, Permanent(errTest)},
+			expectedAttempts: 1,
+			expectedErr:      "after 1 attempts, stopped by permanent errors: [test error test error]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			err := Retry(log, 3, 0, func() []error {
+				attempts++
+				return tt.errs
+			})
+			assert.Equal(t, tt.expectedAttempts, attempts)
+			if tt.expectedErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Equal(t, tt.expectedErr, err.Error())
+		})
+	}
+}